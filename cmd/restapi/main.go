@@ -1,10 +1,27 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"quote-service/internal/auth"
+	"quote-service/internal/health"
+	"quote-service/internal/metrics"
+	"quote-service/internal/ratelimit"
 	hardcodedrepository "quote-service/internal/repository/hardcoded_adapter"
+	metricsrepository "quote-service/internal/repository/metrics_adapter"
+	tracedrepository "quote-service/internal/repository/traced_adapter"
 	"quote-service/internal/restapi"
+	"quote-service/internal/restapi/routes"
 	"quote-service/pkg/authorclient"
 	"quote-service/pkg/logger/slog"
+	"quote-service/pkg/tracing"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -14,33 +31,387 @@ type EnvVars struct {
 	Host string `env:"HOST,required"`
 	Port int    `env:"PORT,required"`
 
-	AuthorServiceURL string `env:"AUTHOR_SERVICE_URL,required"`
+	LogFormat         string            `env:"LOG_FORMAT" envDefault:"json"`
+	LogLevel          string            `env:"LOG_LEVEL" envDefault:"info"`
+	LogLevelOverrides map[string]string `env:"LOG_LEVEL_OVERRIDES" envKeyValSeparator:"="`
+
+	LogRedactKeys         []string `env:"LOG_REDACT_KEYS" envDefault:"authorization,api_key,apikey,x_api_key,token,password,secret,cookie,set_cookie"`
+	LogRedactEmails       bool     `env:"LOG_REDACT_EMAILS" envDefault:"true"`
+	LogRedactBearerTokens bool     `env:"LOG_REDACT_BEARER_TOKENS" envDefault:"true"`
+	LogRedactPatterns     []string `env:"LOG_REDACT_PATTERNS" envSeparator:";"`
+
+	LogSamplingInterval time.Duration     `env:"LOG_SAMPLING_INTERVAL" envDefault:"1s"`
+	LogSamplingLevels   map[string]string `env:"LOG_SAMPLING_LEVELS" envKeyValSeparator:"="`
+
+	LogSinks                []string      `env:"LOG_SINKS" envDefault:"stderr"`
+	LogStderrFormat         string        `env:"LOG_STDERR_FORMAT"`
+	LogStderrLevel          string        `env:"LOG_STDERR_LEVEL"`
+	LogFilePath             string        `env:"LOG_FILE_PATH" envDefault:"logs/quote-service.log"`
+	LogFileFormat           string        `env:"LOG_FILE_FORMAT"`
+	LogFileLevel            string        `env:"LOG_FILE_LEVEL"`
+	LogFileMaxSizeMB        int           `env:"LOG_FILE_MAX_SIZE_MB" envDefault:"100"`
+	LogFileRotationInterval time.Duration `env:"LOG_FILE_ROTATION_INTERVAL" envDefault:"24h"`
+	LogFileMaxBackups       int           `env:"LOG_FILE_MAX_BACKUPS" envDefault:"7"`
+	LogFileMaxAge           time.Duration `env:"LOG_FILE_MAX_AGE" envDefault:"168h"`
+	LogSyslogNetwork        string        `env:"LOG_SYSLOG_NETWORK" envDefault:"udp"`
+	LogSyslogAddress        string        `env:"LOG_SYSLOG_ADDRESS" envDefault:"localhost:514"`
+	LogSyslogFacility       string        `env:"LOG_SYSLOG_FACILITY" envDefault:"local0"`
+	LogSyslogFormat         string        `env:"LOG_SYSLOG_FORMAT"`
+	LogSyslogLevel          string        `env:"LOG_SYSLOG_LEVEL"`
+
+	AuthorServiceURL                string        `env:"AUTHOR_SERVICE_URL,required"`
+	AuthorServiceBreakerFailures    int           `env:"AUTHOR_SERVICE_BREAKER_FAILURES" envDefault:"5"`
+	AuthorServiceBreakerOpenTimeout time.Duration `env:"AUTHOR_SERVICE_BREAKER_OPEN_TIMEOUT" envDefault:"30s"`
+
+	HealthCheckTimeout      time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	ReadinessCriticalChecks []string      `env:"READINESS_CRITICAL_CHECKS" envDefault:"repository"`
+
+	ServerReadTimeout       time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"30s"`
+	ServerReadHeaderTimeout time.Duration `env:"SERVER_READ_HEADER_TIMEOUT" envDefault:"10s"`
+	ServerWriteTimeout      time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"330s"`
+	ServerIdleTimeout       time.Duration `env:"SERVER_IDLE_TIMEOUT" envDefault:"120s"`
+	ServerMaxHeaderBytes    int           `env:"SERVER_MAX_HEADER_BYTES" envDefault:"1048576"`
+
+	TLSCertFile       string        `env:"TLS_CERT_FILE"`
+	TLSKeyFile        string        `env:"TLS_KEY_FILE"`
+	TLSMinVersion     string        `env:"TLS_MIN_VERSION" envDefault:"1.2"`
+	TLSClientCAFile   string        `env:"TLS_CLIENT_CA_FILE"`
+	TLSClientAuth     string        `env:"TLS_CLIENT_AUTH"`
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" envDefault:"30s"`
+
+	CORSAllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" envDefault:"*"`
+	CORSAllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" envDefault:"Content-Type,Authorization,X-Request-ID"`
+	CORSExposedHeaders   []string      `env:"CORS_EXPOSED_HEADERS" envDefault:"X-Request-ID"`
+	CORSAllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" envDefault:"false"`
+	CORSMaxAge           time.Duration `env:"CORS_MAX_AGE" envDefault:"1h"`
+
+	AuthAPIKeys     []string `env:"AUTH_API_KEYS"`
+	AuthAPIKeysFile string   `env:"AUTH_API_KEYS_FILE"`
+	AuthJWTSecret   string   `env:"AUTH_JWT_SECRET"`
+	AuthJWKSFile    string   `env:"AUTH_JWKS_FILE"`
+	AuthJWTIssuer   string   `env:"AUTH_JWT_ISSUER"`
+	AuthJWTAudience string   `env:"AUTH_JWT_AUDIENCE"`
+	AuthDisabled    bool     `env:"AUTH_DISABLED" envDefault:"false"`
+
+	RateLimitEnabled                bool    `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	RateLimitDefaultRate            float64 `env:"RATE_LIMIT_DEFAULT_RATE" envDefault:"50"`
+	RateLimitDefaultBurst           int     `env:"RATE_LIMIT_DEFAULT_BURST" envDefault:"100"`
+	RateLimitExpensiveRate          float64 `env:"RATE_LIMIT_EXPENSIVE_RATE" envDefault:"2"`
+	RateLimitExpensiveBurst         int     `env:"RATE_LIMIT_EXPENSIVE_BURST" envDefault:"10"`
+	RateLimitExpensiveMaxConcurrent int     `env:"RATE_LIMIT_EXPENSIVE_MAX_CONCURRENT" envDefault:"10"`
+	RateLimitAuthFailureRate        float64 `env:"RATE_LIMIT_AUTH_FAILURE_RATE" envDefault:"0.1"`
+	RateLimitAuthFailureBurst       int     `env:"RATE_LIMIT_AUTH_FAILURE_BURST" envDefault:"10"`
+
+	TrustProxyHeaders bool `env:"TRUST_PROXY_HEADERS" envDefault:"false"`
+	TrustedProxyHops  int  `env:"TRUSTED_PROXY_HOPS" envDefault:"1"`
+
+	AccessLogEnabled       bool          `env:"ACCESS_LOG_ENABLED" envDefault:"true"`
+	AccessLogSampleRate    float64       `env:"ACCESS_LOG_SAMPLE_RATE" envDefault:"1"`
+	AccessLogSlowThreshold time.Duration `env:"ACCESS_LOG_SLOW_THRESHOLD" envDefault:"1s"`
+	AccessLogExcludedPaths []string      `env:"ACCESS_LOG_EXCLUDED_PATHS" envDefault:"/healthz,/readyz,/metrics"`
+	AccessLogFields        []string      `env:"ACCESS_LOG_FIELDS"`
+
+	CompressionEnabled   bool     `env:"COMPRESSION_ENABLED" envDefault:"true"`
+	CompressionMinSize   int      `env:"COMPRESSION_MIN_SIZE" envDefault:"1024"`
+	CompressionEncodings []string `env:"COMPRESSION_ENCODINGS" envDefault:"br,zstd,gzip"`
+
+	CacheControlQuoteByID   string `env:"CACHE_CONTROL_QUOTE_BY_ID" envDefault:"public, max-age=86400"`
+	CacheControlQuoteRandom string `env:"CACHE_CONTROL_QUOTE_RANDOM" envDefault:"no-store"`
+
+	FaultInjectionEnabled      bool `env:"FAULT_INJECTION_ENABLED" envDefault:"false"`
+	FaultInjectionAllowHeaders bool `env:"FAULT_INJECTION_ALLOW_HEADERS" envDefault:"false"`
+
+	MockMemoryBudgetMB     int           `env:"MOCK_MEMORY_BUDGET_MB" envDefault:"2048"`
+	MockMemoryAdmission    string        `env:"MOCK_MEMORY_ADMISSION" envDefault:"reject"`
+	MockMemoryQueueTimeout time.Duration `env:"MOCK_MEMORY_QUEUE_TIMEOUT" envDefault:"30s"`
+	MockMemoryMaxJobs      int           `env:"MOCK_MEMORY_MAX_JOBS" envDefault:"10"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+
+	TracingExporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
 }
 
+const version = "v0.0.6"
+
 func main() {
-	logger := slog.NewLogger(slog.NewLoggerArgs{
-		LogFormat: "json",
+	envVars, err := env.ParseAs[EnvVars]()
+	if err != nil {
+		panic(err)
+	}
+
+	logLevel, err := slog.ParseLevel(envVars.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	componentLevels := make(map[string]slog.Level, len(envVars.LogLevelOverrides))
+	for component, level := range envVars.LogLevelOverrides {
+		if componentLevels[component], err = slog.ParseLevel(level); err != nil {
+			panic(err)
+		}
+	}
+
+	logger, err := slog.NewLogger(slog.NewLoggerArgs{
+		LogFormat:       envVars.LogFormat,
+		Level:           logLevel,
+		ComponentLevels: componentLevels,
+		Redaction: slog.RedactionConfig{
+			Keys:         envVars.LogRedactKeys,
+			Emails:       envVars.LogRedactEmails,
+			BearerTokens: envVars.LogRedactBearerTokens,
+			Patterns:     envVars.LogRedactPatterns,
+		},
+		Sinks:    logSinks(envVars),
+		Sampling: logSampling(envVars),
 	})
+	if err != nil {
+		panic(err)
+	}
 
-	envVars, err := env.ParseAs[EnvVars]()
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.SetupArgs{
+		Exporter:       envVars.TracingExporter,
+		SampleRatio:    envVars.TracingSampleRatio,
+		ServiceName:    "quote-service",
+		ServiceVersion: version,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	repo := hardcodedrepository.NewHardcodedRepository()
+	appMetrics := metrics.New()
+
+	repo := tracedrepository.NewTracedRepository(
+		metricsrepository.NewMetricsRepository(hardcodedrepository.NewHardcodedRepository(), appMetrics),
+	)
 
 	authorClient := authorclient.NewClient(authorclient.NewClientConfig{
-		BaseURL: envVars.AuthorServiceURL,
-		Timeout: time.Second * 10,
+		BaseURL:   envVars.AuthorServiceURL,
+		Timeout:   time.Second * 10,
+		Transport: appMetrics.InstrumentAuthorServiceTransport(http.DefaultTransport),
+		Breaker: authorclient.BreakerConfig{
+			FailureThreshold: envVars.AuthorServiceBreakerFailures,
+			OpenTimeout:      envVars.AuthorServiceBreakerOpenTimeout,
+		},
 	})
 
+	readinessChecks := map[string]health.CheckFunc{
+		"repository": repo.Ping,
+		// Reports the circuit state instead of calling the author-service on every probe
+		"author-service": authorClient.CheckCircuit,
+	}
+	for _, name := range envVars.ReadinessCriticalChecks {
+		if _, ok := readinessChecks[name]; !ok {
+			panic(fmt.Sprintf("unknown readiness check %q in READINESS_CRITICAL_CHECKS, must be one of repository, author-service", name))
+		}
+	}
+
+	checker := health.NewChecker(envVars.HealthCheckTimeout)
+	checker.AddCheck("repository", slices.Contains(envVars.ReadinessCriticalChecks, "repository"), readinessChecks["repository"])
+	checker.AddCheck("author-service", slices.Contains(envVars.ReadinessCriticalChecks, "author-service"), readinessChecks["author-service"])
+
+	authenticator, err := auth.NewAuthenticator(auth.Config{
+		APIKeys:     envVars.AuthAPIKeys,
+		APIKeysFile: envVars.AuthAPIKeysFile,
+		JWTSecret:   envVars.AuthJWTSecret,
+		JWKSFile:    envVars.AuthJWKSFile,
+		JWTIssuer:   envVars.AuthJWTIssuer,
+		JWTAudience: envVars.AuthJWTAudience,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	var memoryBudget *routes.MemoryBudget
+	if envVars.MockMemoryBudgetMB > 0 {
+		if envVars.MockMemoryAdmission != routes.AdmissionReject && envVars.MockMemoryAdmission != routes.AdmissionQueue {
+			panic("MOCK_MEMORY_ADMISSION must be " + routes.AdmissionReject + " or " + routes.AdmissionQueue)
+		}
+		memoryBudget = routes.NewMemoryBudget(envVars.MockMemoryBudgetMB, envVars.MockMemoryAdmission, envVars.MockMemoryQueueTimeout)
+	}
+
 	app := &restapi.App{
-		Version:      "v0.0.6",
-		Logger:       logger,
-		Repository:   repo,
-		AuthorClient: authorClient,
-		Port:         envVars.Port,
-		Host:         envVars.Host,
-	}
-	app.SetupAndRun()
+		Version:       version,
+		Logger:        logger.Named("restapi"),
+		LogLevels:     logger,
+		Repository:    repo,
+		AuthorClient:  authorClient,
+		Metrics:       appMetrics,
+		Health:        checker,
+		Auth:          authenticator,
+		AuthDisabled:  envVars.AuthDisabled,
+		MemoryBudget:  memoryBudget,
+		MaxMemoryJobs: envVars.MockMemoryMaxJobs,
+		Port:          envVars.Port,
+		Host:          envVars.Host,
+
+		ReadTimeout:       envVars.ServerReadTimeout,
+		ReadHeaderTimeout: envVars.ServerReadHeaderTimeout,
+		WriteTimeout:      envVars.ServerWriteTimeout,
+		IdleTimeout:       envVars.ServerIdleTimeout,
+		MaxHeaderBytes:    envVars.ServerMaxHeaderBytes,
+
+		TLS: restapi.TLSConfig{
+			CertFile:       envVars.TLSCertFile,
+			KeyFile:        envVars.TLSKeyFile,
+			MinVersion:     envVars.TLSMinVersion,
+			ClientCAFile:   envVars.TLSClientCAFile,
+			ClientAuth:     envVars.TLSClientAuth,
+			ReloadInterval: envVars.TLSReloadInterval,
+		},
+
+		CORS: restapi.CORSConfig{
+			AllowedOrigins:   envVars.CORSAllowedOrigins,
+			AllowedHeaders:   envVars.CORSAllowedHeaders,
+			ExposedHeaders:   envVars.CORSExposedHeaders,
+			AllowCredentials: envVars.CORSAllowCredentials,
+			MaxAge:           envVars.CORSMaxAge,
+		},
+
+		RateLimit: restapi.RateLimitConfig{
+			Enabled:                envVars.RateLimitEnabled,
+			Default:                ratelimit.Limit{Rate: envVars.RateLimitDefaultRate, Burst: envVars.RateLimitDefaultBurst},
+			Expensive:              ratelimit.Limit{Rate: envVars.RateLimitExpensiveRate, Burst: envVars.RateLimitExpensiveBurst},
+			ExpensiveMaxConcurrent: envVars.RateLimitExpensiveMaxConcurrent,
+			AuthFailures:           ratelimit.Limit{Rate: envVars.RateLimitAuthFailureRate, Burst: envVars.RateLimitAuthFailureBurst},
+		},
+		TrustProxyHeaders: envVars.TrustProxyHeaders,
+		TrustedProxyHops:  envVars.TrustedProxyHops,
+
+		AccessLog: restapi.AccessLogConfig{
+			Enabled:       envVars.AccessLogEnabled,
+			SampleRate:    envVars.AccessLogSampleRate,
+			SlowThreshold: envVars.AccessLogSlowThreshold,
+			ExcludedPaths: envVars.AccessLogExcludedPaths,
+			Fields:        envVars.AccessLogFields,
+		},
+		Compression: restapi.CompressionConfig{
+			Enabled:   envVars.CompressionEnabled,
+			MinSize:   envVars.CompressionMinSize,
+			Encodings: envVars.CompressionEncodings,
+		},
+		CacheControl: cacheControlPolicies(envVars),
+
+		FaultInjection: restapi.FaultInjectionConfig{
+			Enabled:      envVars.FaultInjectionEnabled,
+			AllowHeaders: envVars.FaultInjectionAllowHeaders,
+		},
+
+		ShutdownTimeout: envVars.ShutdownTimeout,
+		ShutdownDelay:   envVars.ShutdownDelay,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	runErr := app.SetupAndRun(ctx)
+
+	// Flush buffered spans before exiting
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("Failed to flush traces", "error", err.Error())
+	}
+	if err := logger.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to close log sinks:", err)
+	}
+
+	if runErr != nil {
+		os.Exit(1)
+	}
+}
+
+// logSinks builds the sink configs of LOG_SINKS. It panics on invalid sink levels, like the other
+// log settings.
+func logSinks(envVars EnvVars) []slog.SinkConfig {
+	sinkLevel := func(level string) *slog.Level {
+		if level == "" {
+			return nil
+		}
+		parsed, err := slog.ParseLevel(level)
+		if err != nil {
+			panic(err)
+		}
+		return &parsed
+	}
+
+	sinks := make([]slog.SinkConfig, 0, len(envVars.LogSinks))
+	for _, sinkType := range envVars.LogSinks {
+		switch sinkType {
+		case slog.SinkStderr:
+			sinks = append(sinks, slog.SinkConfig{
+				Type:   sinkType,
+				Format: envVars.LogStderrFormat,
+				Level:  sinkLevel(envVars.LogStderrLevel),
+			})
+		case slog.SinkFile:
+			sinks = append(sinks, slog.SinkConfig{
+				Type:   sinkType,
+				Format: envVars.LogFileFormat,
+				Level:  sinkLevel(envVars.LogFileLevel),
+				File: slog.FileSinkConfig{
+					Path:             envVars.LogFilePath,
+					MaxSizeMB:        envVars.LogFileMaxSizeMB,
+					RotationInterval: envVars.LogFileRotationInterval,
+					MaxBackups:       envVars.LogFileMaxBackups,
+					MaxAge:           envVars.LogFileMaxAge,
+				},
+			})
+		case slog.SinkSyslog:
+			sinks = append(sinks, slog.SinkConfig{
+				Type:   sinkType,
+				Format: envVars.LogSyslogFormat,
+				Level:  sinkLevel(envVars.LogSyslogLevel),
+				Syslog: slog.SyslogSinkConfig{
+					Network:  envVars.LogSyslogNetwork,
+					Address:  envVars.LogSyslogAddress,
+					Facility: envVars.LogSyslogFacility,
+					AppName:  "quote-service",
+				},
+			})
+		default:
+			// Reported by NewLogger
+			sinks = append(sinks, slog.SinkConfig{Type: sinkType})
+		}
+	}
+
+	return sinks
+}
+
+// logSampling builds the sampling config of LOG_SAMPLING_LEVELS, whose values are "first:thereafter"
+// like "error=10:100". It panics on invalid values, like the other log settings.
+func logSampling(envVars EnvVars) slog.SamplingConfig {
+	levels := make(map[slog.Level]slog.SamplingPolicy, len(envVars.LogSamplingLevels))
+	for levelName, policy := range envVars.LogSamplingLevels {
+		level, err := slog.ParseLevel(levelName)
+		if err != nil {
+			panic(err)
+		}
+
+		firstStr, thereafterStr, ok := strings.Cut(policy, ":")
+		first, firstErr := strconv.Atoi(firstStr)
+		thereafter, thereafterErr := strconv.Atoi(thereafterStr)
+		if !ok || firstErr != nil || thereafterErr != nil {
+			panic(fmt.Sprintf("invalid log sampling %q for %s, must be first:thereafter", policy, levelName))
+		}
+		levels[level] = slog.SamplingPolicy{First: first, Thereafter: thereafter}
+	}
+
+	return slog.SamplingConfig{Interval: envVars.LogSamplingInterval, Levels: levels}
+}
+
+// cacheControlPolicies maps the routes with a Cache-Control policy to it, empty policies are left
+// out
+func cacheControlPolicies(envVars EnvVars) map[string]string {
+	policies := map[string]string{
+		"GET /api/quote/{id}":   envVars.CacheControlQuoteByID,
+		"GET /api/quote/random": envVars.CacheControlQuoteRandom,
+	}
+	for pattern, policy := range policies {
+		if policy == "" {
+			delete(policies, pattern)
+		}
+	}
+
+	return policies
 }
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Level is the authentication requirement of a route
+type Level int
+
+const (
+	// Public routes are reachable without credentials
+	Public Level = iota
+	// Admin routes require a principal with the admin role
+	Admin
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidAPIKeyEntry = errors.New("invalid API key entry")
+)
+
+const apiKeyHeader = "X-API-Key"
+
+type Config struct {
+	// APIKeys are "name:roles:sha256hex" entries where roles is a "|" separated list (may be
+	// empty) and sha256hex is the hex encoded SHA-256 hash of the key.
+	APIKeys []string
+	// APIKeysFile contains additional API key entries, one per line. Empty lines and lines
+	// starting with "#" are ignored.
+	APIKeysFile string
+
+	// JWTSecret verifies HMAC signed (HS256/384/512) bearer tokens
+	JWTSecret string
+	// JWKSFile is a JSON Web Key Set used to verify RSA and ECDSA signed bearer tokens
+	JWKSFile string
+	// JWTIssuer and JWTAudience, when set, must match the token's "iss" and "aud" claims
+	JWTIssuer   string
+	JWTAudience string
+}
+
+type apiKey struct {
+	name  string
+	roles []string
+}
+
+// Authenticator verifies the API key or bearer token of a request
+type Authenticator struct {
+	// apiKeys is indexed by the SHA-256 hash of the key so plain keys never need to be stored
+	apiKeys map[[sha256.Size]byte]apiKey
+
+	jwtSecret []byte
+	jwks      *jwks
+	parser    *jwt.Parser
+}
+
+// NewAuthenticator creates an Authenticator from config. Authentication is disabled when no API
+// keys, JWT secret or JWKS are configured.
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:   make(map[[sha256.Size]byte]apiKey),
+		jwtSecret: []byte(config.JWTSecret),
+	}
+
+	entries := config.APIKeys
+	if config.APIKeysFile != "" {
+		fileEntries, err := readAPIKeysFile(config.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		entries = slices.Concat(entries, fileEntries)
+	}
+	for _, entry := range entries {
+		hash, key, err := parseAPIKeyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		a.apiKeys[hash] = key
+	}
+
+	if config.JWKSFile != "" {
+		keys, err := loadJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = keys
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(a.validMethods()),
+		jwt.WithExpirationRequired(),
+	}
+	if config.JWTIssuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(config.JWTIssuer))
+	}
+	if config.JWTAudience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(config.JWTAudience))
+	}
+	a.parser = jwt.NewParser(parserOptions...)
+
+	return a, nil
+}
+
+// Enabled reports whether any credentials are configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.apiKeys) > 0 || len(a.jwtSecret) > 0 || a.jwks != nil
+}
+
+// HasCredentials reports whether the request carries an API key or a bearer token, i.e. whether
+// Authenticate would verify something rather than return ErrNoCredentials
+func HasCredentials(r *http.Request) bool {
+	if r.Header.Get(apiKeyHeader) != "" {
+		return true
+	}
+
+	scheme, _, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	return ok && (strings.EqualFold(scheme, "apikey") || strings.EqualFold(scheme, "bearer"))
+}
+
+// Authenticate returns the principal of the request. It returns ErrNoCredentials if the request
+// has neither an API key nor a bearer token and ErrInvalidCredentials if they don't verify.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	switch strings.ToLower(scheme) {
+	case "apikey":
+		return a.authenticateAPIKey(strings.TrimSpace(credentials))
+	case "bearer":
+		return a.authenticateJWT(strings.TrimSpace(credentials))
+	default:
+		return nil, ErrNoCredentials
+	}
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	entry, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{Subject: entry.name, Method: MethodAPIKey, Roles: entry.roles}, nil
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Role  string   `json:"role,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+}
+
+// roles collects roles from the "roles", "role" and space separated "scope" claims
+func (c *claims) roles() []string {
+	roles := append([]string{}, c.Roles...)
+	if c.Role != "" {
+		roles = append(roles, c.Role)
+	}
+	roles = append(roles, strings.Fields(c.Scope)...)
+
+	return roles
+}
+
+func (a *Authenticator) authenticateJWT(token string) (*Principal, error) {
+	if len(a.jwtSecret) == 0 && a.jwks == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var c claims
+	if _, err := a.parser.ParseWithClaims(token, &c, a.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: c.Subject, Method: MethodJWT, Roles: c.roles()}, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(a.jwtSecret) == 0 {
+			return nil, errors.New("HMAC signed tokens are not accepted")
+		}
+		return a.jwtSecret, nil
+	}
+
+	if a.jwks == nil {
+		return nil, errors.New("asymmetric signed tokens are not accepted")
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return a.jwks.key(kid)
+}
+
+func (a *Authenticator) validMethods() []string {
+	var methods []string
+	if len(a.jwtSecret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if a.jwks != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512")
+	}
+
+	return methods
+}
+
+func parseAPIKeyEntry(entry string) ([sha256.Size]byte, apiKey, error) {
+	var hash [sha256.Size]byte
+
+	parts := strings.Split(strings.TrimSpace(entry), ":")
+	if len(parts) != 3 || parts[0] == "" { //nolint:mnd
+		return hash, apiKey{}, fmt.Errorf("%w: expected name:roles:sha256hex", ErrInvalidAPIKeyEntry)
+	}
+
+	decoded, err := hex.DecodeString(parts[2])
+	if err != nil || len(decoded) != sha256.Size {
+		return hash, apiKey{}, fmt.Errorf("%w: %s: hash must be a hex encoded SHA-256", ErrInvalidAPIKeyEntry, parts[0])
+	}
+	copy(hash[:], decoded)
+
+	var roles []string
+	if parts[1] != "" {
+		roles = strings.Split(parts[1], "|")
+	}
+
+	return hash, apiKey{name: parts[0], roles: roles}, nil
+}
+
+func readAPIKeysFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,364 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-test-secret-test-secret"
+
+// testKeys are the signing keys of the JWKS written by writeJWKS
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+// writeJWKS writes the public RSA key as "rsa-1" and, unless rsaOnly is set, the public EC key
+// as "ec-1" to a JWKS file and returns its path
+func writeJWKS(t *testing.T, keys testKeys, rsaOnly bool) string {
+	t.Helper()
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	set := map[string][]map[string]string{"keys": {{
+		"kid": "rsa-1", "kty": "RSA", "use": "sig",
+		"n": encode(keys.rsa.N.Bytes()), "e": encode(big.NewInt(int64(keys.rsa.E)).Bytes()),
+	}}}
+	if !rsaOnly {
+		set["keys"] = append(set["keys"], map[string]string{
+			"kid": "ec-1", "kty": "EC", "crv": "P-256",
+			"x": encode(keys.ec.X.FillBytes(make([]byte, 32))), "y": encode(keys.ec.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sign creates a token signed with key, adding kid to its header unless it's empty
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// validClaims returns claims that pass all checks of an authenticator with issuer "issuer" and
+// audience "quote-service"
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "jane",
+		"iss":   "issuer",
+		"aud":   "quote-service",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+	}
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	keys := newTestKeys(t)
+	authenticator, err := NewAuthenticator(Config{
+		JWTSecret:   testSecret,
+		JWKSFile:    writeJWKS(t, keys, false),
+		JWTIssuer:   "issuer",
+		JWTAudience: "quote-service",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withClaim := func(key string, value any) jwt.MapClaims {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "HMAC", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims())},
+		{name: "RSA", token: sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims())},
+		{name: "ECDSA", token: sign(t, jwt.SigningMethodES256, keys.ec, "ec-1", validClaims())},
+		{
+			name:    "wrong secret",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("other-secret"), "", validClaims()),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "unknown kid",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-2", validClaims()),
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name:    "no kid with several keys",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "", validClaims()),
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name:    "RSA key ID on an ECDSA token",
+			token:   sign(t, jwt.SigningMethodES256, keys.ec, "rsa-1", validClaims()),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "expired",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", time.Now().Add(-time.Minute).Unix())),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:    "no expiration",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", nil)),
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "issuer mismatch",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("iss", "other-issuer")),
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:    "audience mismatch",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("aud", "other-service")),
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:    "no subject",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("sub", nil)),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "unsigned",
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+			wantErr: ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(bearerRequest(tt.token))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Authenticate() error = %v, want %v and ErrInvalidCredentials", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if principal.Subject != "jane" || principal.Method != MethodJWT || !principal.IsAdmin() {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+// TestAuthenticateJWTAlgorithmConfusion checks that a public key is never used as an HMAC secret
+// and that asymmetric tokens aren't accepted without a JWKS
+func TestAuthenticateJWTAlgorithmConfusion(t *testing.T) {
+	keys := newTestKeys(t)
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&keys.rsa.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwksOnly, err := NewAuthenticator(Config{JWKSFile: writeJWKS(t, keys, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretOnly, err := NewAuthenticator(Config{JWTSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		token         string
+	}{
+		{
+			name:          "HS256 signed with the RSA public key",
+			authenticator: jwksOnly,
+			token:         sign(t, jwt.SigningMethodHS256, publicKeyDER, "rsa-1", validClaims()),
+		},
+		{
+			name:          "HS256 signed with the RSA modulus",
+			authenticator: jwksOnly,
+			token:         sign(t, jwt.SigningMethodHS256, keys.rsa.N.Bytes(), "", validClaims()),
+		},
+		{
+			name:          "RS256 without a JWKS",
+			authenticator: secretOnly,
+			token:         sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.authenticator.Authenticate(bearerRequest(tt.token)); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+				t.Errorf("Authenticate() error = %v, want jwt.ErrTokenSignatureInvalid", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateJWTSingleKeyWithoutKid(t *testing.T) {
+	keys := newTestKeys(t)
+	authenticator, err := NewAuthenticator(Config{JWKSFile: writeJWKS(t, keys, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authenticator.Authenticate(bearerRequest(sign(t, jwt.SigningMethodRS256, keys.rsa, "", validClaims()))); err != nil {
+		t.Errorf("token without kid: Authenticate() error = %v", err)
+	}
+	if _, err := authenticator.Authenticate(bearerRequest(sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-2", validClaims()))); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("token with an unknown kid: Authenticate() error = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	hash := sha256.Sum256([]byte("s3cr3t"))
+	keysFile := filepath.Join(t.TempDir(), "api-keys")
+	content := "# ops keys\n\nreader::" + hex.EncodeToString(hash[:]) + "\n"
+	if err := os.WriteFile(keysFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	otherHash := sha256.Sum256([]byte("other"))
+
+	authenticator, err := NewAuthenticator(Config{
+		APIKeys:     []string{"ops:admin|deploy:" + hex.EncodeToString(otherHash[:])},
+		APIKeysFile: keysFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		wantName  string
+		wantRoles []string
+		wantErr   error
+	}{
+		{name: "X-API-Key from the file", header: "X-API-Key", value: "s3cr3t", wantName: "reader"},
+		{name: "ApiKey scheme", header: "Authorization", value: "ApiKey other", wantName: "ops", wantRoles: []string{"admin", "deploy"}},
+		{name: "unknown key", header: "X-API-Key", value: "guess", wantErr: ErrInvalidCredentials},
+		{name: "no credentials", wantErr: ErrNoCredentials},
+		{name: "other scheme", header: "Authorization", value: "Basic b3BzOnMzY3IzdA==", wantErr: ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if principal.Subject != tt.wantName || principal.Method != MethodAPIKey || !slices.Equal(principal.Roles, tt.wantRoles) {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestParseAPIKeyEntry(t *testing.T) {
+	hash := sha256.Sum256([]byte("s3cr3t"))
+	hexHash := hex.EncodeToString(hash[:])
+
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "valid", entry: "ops:admin:" + hexHash},
+		{name: "valid without roles", entry: " reader::" + hexHash + " "},
+		{name: "missing hash", entry: "ops:admin", wantErr: true},
+		{name: "too many parts", entry: "ops:admin:" + hexHash + ":extra", wantErr: true},
+		{name: "empty name", entry: ":admin:" + hexHash, wantErr: true},
+		{name: "not hex", entry: "ops:admin:" + hexHash[:62] + "zz", wantErr: true},
+		{name: "short hash", entry: "ops:admin:" + hexHash[:32], wantErr: true},
+		{name: "plain key", entry: "ops:admin:s3cr3t", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHash, _, err := parseAPIKeyEntry(tt.entry)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAPIKeyEntry) {
+					t.Errorf("parseAPIKeyEntry() error = %v, want ErrInvalidAPIKeyEntry", err)
+				}
+				return
+			}
+			if err != nil || gotHash != hash {
+				t.Errorf("parseAPIKeyEntry() = %x, %v", gotHash, err)
+			}
+		})
+	}
+
+	if _, err := NewAuthenticator(Config{APIKeys: []string{"ops:admin"}}); !errors.Is(err, ErrInvalidAPIKeyEntry) {
+		t.Errorf("NewAuthenticator() error = %v, want ErrInvalidAPIKeyEntry", err)
+	}
+}
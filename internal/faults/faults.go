@@ -0,0 +1,221 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyRoute is the route of a rule that applies to every route without a rule of its own
+const AnyRoute = "*"
+
+// Request headers that inject faults into a single request
+const (
+	HeaderLatencyMS       = "X-Fault-Latency-Ms"
+	HeaderLatencyJitterMS = "X-Fault-Latency-Jitter-Ms"
+	HeaderErrorPercent    = "X-Fault-Error-Percent"
+	HeaderErrorStatus     = "X-Fault-Error-Status"
+	HeaderDropPercent     = "X-Fault-Drop-Percent"
+	HeaderSlowBodyBPS     = "X-Fault-Slow-Body-Bps"
+)
+
+const (
+	// maxLatencyMS caps injected latency so a typo can't park requests for hours
+	maxLatencyMS = 60_000
+	// maxSlowBodyBytesPerSecond caps body throttling, faster rates aren't slow anymore
+	maxSlowBodyBytesPerSecond = 100 * 1024 * 1024
+)
+
+var (
+	ErrInvalidRule    = errors.New("invalid fault rule")
+	ErrDuplicateRoute = errors.New("duplicate fault rule route")
+)
+
+// Rule describes the faults injected into requests to Route, a route pattern like
+// "GET /api/quote/{id}" or AnyRoute
+type Rule struct {
+	Route string `json:"route"`
+	// LatencyMS is added before the handler runs, plus a random 0-LatencyJitterMS
+	LatencyMS       int `json:"latency_ms,omitempty"`
+	LatencyJitterMS int `json:"latency_jitter_ms,omitempty"`
+	// ErrorPercent of requests are answered with ErrorStatus (default 500) instead
+	ErrorPercent float64 `json:"error_percent,omitempty"`
+	ErrorStatus  int     `json:"error_status,omitempty"`
+	// DropPercent of requests have their connection closed without a response
+	DropPercent float64 `json:"drop_percent,omitempty"`
+	// SlowBodyBytesPerSecond throttles the response body, 0 for no throttling
+	SlowBodyBytesPerSecond int `json:"slow_body_bytes_per_second,omitempty"`
+}
+
+// Validate checks that every field of the rule is in range
+func (rule Rule) Validate() error {
+	switch {
+	case rule.Route == "":
+		return fmt.Errorf("%w: route is required", ErrInvalidRule)
+	case rule.LatencyMS < 0 || rule.LatencyMS > maxLatencyMS:
+		return fmt.Errorf("%w: latency_ms must be between 0 and %d", ErrInvalidRule, maxLatencyMS)
+	case rule.LatencyJitterMS < 0 || rule.LatencyJitterMS > maxLatencyMS:
+		return fmt.Errorf("%w: latency_jitter_ms must be between 0 and %d", ErrInvalidRule, maxLatencyMS)
+	case rule.ErrorPercent < 0 || rule.ErrorPercent > 100:
+		return fmt.Errorf("%w: error_percent must be between 0 and 100", ErrInvalidRule)
+	case rule.ErrorStatus != 0 && (rule.ErrorStatus < 500 || rule.ErrorStatus > 599):
+		return fmt.Errorf("%w: error_status must be between 500 and 599", ErrInvalidRule)
+	case rule.DropPercent < 0 || rule.DropPercent > 100:
+		return fmt.Errorf("%w: drop_percent must be between 0 and 100", ErrInvalidRule)
+	case rule.SlowBodyBytesPerSecond < 0 || rule.SlowBodyBytesPerSecond > maxSlowBodyBytesPerSecond:
+		return fmt.Errorf("%w: slow_body_bytes_per_second must be between 0 and %d", ErrInvalidRule, maxSlowBodyBytesPerSecond)
+	}
+	return nil
+}
+
+// Latency returns the latency to inject into one request, jitter included
+func (rule Rule) Latency() time.Duration {
+	latency := time.Duration(rule.LatencyMS) * time.Millisecond
+	if rule.LatencyJitterMS > 0 {
+		latency += time.Duration(rand.IntN(rule.LatencyJitterMS+1)) * time.Millisecond
+	}
+	return latency
+}
+
+// ShouldFail rolls whether one request gets an error response
+func (rule Rule) ShouldFail() bool {
+	return rule.ErrorPercent > 0 && rand.Float64()*100 < rule.ErrorPercent
+}
+
+// ShouldDrop rolls whether one request gets its connection dropped
+func (rule Rule) ShouldDrop() bool {
+	return rule.DropPercent > 0 && rand.Float64()*100 < rule.DropPercent
+}
+
+// Status returns the status code of injected errors
+func (rule Rule) Status() int {
+	if rule.ErrorStatus == 0 {
+		return http.StatusInternalServerError
+	}
+	return rule.ErrorStatus
+}
+
+// FromHeaders reads a rule for route from the X-Fault-* headers of a request. The boolean is
+// false when the request has none of them.
+func FromHeaders(route string, header http.Header) (Rule, bool, error) {
+	rule := Rule{Route: route}
+	found := false
+
+	intFields := []struct {
+		header string
+		dst    *int
+	}{
+		{HeaderLatencyMS, &rule.LatencyMS},
+		{HeaderLatencyJitterMS, &rule.LatencyJitterMS},
+		{HeaderErrorStatus, &rule.ErrorStatus},
+		{HeaderSlowBodyBPS, &rule.SlowBodyBytesPerSecond},
+	}
+	for _, field := range intFields {
+		value := header.Get(field.header)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return Rule{}, false, fmt.Errorf("%w: %s must be an integer", ErrInvalidRule, field.header)
+		}
+		*field.dst = n
+		found = true
+	}
+
+	floatFields := []struct {
+		header string
+		dst    *float64
+	}{
+		{HeaderErrorPercent, &rule.ErrorPercent},
+		{HeaderDropPercent, &rule.DropPercent},
+	}
+	for _, field := range floatFields {
+		value := header.Get(field.header)
+		if value == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return Rule{}, false, fmt.Errorf("%w: %s must be a number", ErrInvalidRule, field.header)
+		}
+		*field.dst = f
+		found = true
+	}
+
+	if !found {
+		return Rule{}, false, nil
+	}
+	if err := rule.Validate(); err != nil {
+		return Rule{}, false, err
+	}
+	return rule, true, nil
+}
+
+// Injector holds the fault rules configured at runtime
+type Injector struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// NewInjector creates an Injector without rules
+func NewInjector() *Injector {
+	return &Injector{rules: make(map[string]Rule)}
+}
+
+// SetRules replaces all rules. Nothing changes if any rule is invalid.
+func (inj *Injector) SetRules(rules []Rule) error {
+	byRoute := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if _, ok := byRoute[rule.Route]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateRoute, rule.Route)
+		}
+		byRoute[rule.Route] = rule
+	}
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.rules = byRoute
+	return nil
+}
+
+// Rules returns all rules sorted by route
+func (inj *Injector) Rules() []Rule {
+	inj.mu.RLock()
+	defer inj.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(inj.rules))
+	for _, rule := range inj.rules {
+		rules = append(rules, rule)
+	}
+	slices.SortFunc(rules, func(a, b Rule) int { return strings.Compare(a.Route, b.Route) })
+	return rules
+}
+
+// Clear removes all rules
+func (inj *Injector) Clear() {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.rules = make(map[string]Rule)
+}
+
+// Match returns the rule of route, falling back to the AnyRoute rule
+func (inj *Injector) Match(route string) (Rule, bool) {
+	inj.mu.RLock()
+	defer inj.mu.RUnlock()
+
+	if rule, ok := inj.rules[route]; ok {
+		return rule, true
+	}
+	rule, ok := inj.rules[AnyRoute]
+	return rule, ok
+}
@@ -0,0 +1,116 @@
+package faults
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "empty rule", rule: Rule{Route: AnyRoute}},
+		{name: "no route", rule: Rule{}, wantErr: true},
+		{name: "max latency", rule: Rule{Route: AnyRoute, LatencyMS: maxLatencyMS, LatencyJitterMS: maxLatencyMS}},
+		{name: "latency too high", rule: Rule{Route: AnyRoute, LatencyMS: maxLatencyMS + 1}, wantErr: true},
+		{name: "negative jitter", rule: Rule{Route: AnyRoute, LatencyJitterMS: -1}, wantErr: true},
+		{name: "error percent bounds", rule: Rule{Route: AnyRoute, ErrorPercent: 100, DropPercent: 100}},
+		{name: "error percent too high", rule: Rule{Route: AnyRoute, ErrorPercent: 100.1}, wantErr: true},
+		{name: "drop percent negative", rule: Rule{Route: AnyRoute, DropPercent: -1}, wantErr: true},
+		{name: "error status", rule: Rule{Route: AnyRoute, ErrorStatus: 503}},
+		{name: "non-5xx error status", rule: Rule{Route: AnyRoute, ErrorStatus: 404}, wantErr: true},
+		{name: "max slow body rate", rule: Rule{Route: AnyRoute, SlowBodyBytesPerSecond: maxSlowBodyBytesPerSecond}},
+		{name: "slow body rate too high", rule: Rule{Route: AnyRoute, SlowBodyBytesPerSecond: maxSlowBodyBytesPerSecond + 1}, wantErr: true},
+		{name: "negative slow body rate", rule: Rule{Route: AnyRoute, SlowBodyBytesPerSecond: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr != (err != nil) || err != nil && !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		want      Rule
+		wantFound bool
+		wantErr   bool
+	}{
+		{name: "no fault headers", header: http.Header{"Accept": {"*/*"}}},
+		{
+			name: "all headers",
+			header: http.Header{
+				HeaderLatencyMS:       {"100"},
+				HeaderLatencyJitterMS: {" 50 "},
+				HeaderErrorPercent:    {"12.5"},
+				HeaderErrorStatus:     {"503"},
+				HeaderDropPercent:     {"1"},
+				HeaderSlowBodyBPS:     {"1024"},
+			},
+			want: Rule{
+				Route: "GET /api/quote/random", LatencyMS: 100, LatencyJitterMS: 50, ErrorPercent: 12.5,
+				ErrorStatus: 503, DropPercent: 1, SlowBodyBytesPerSecond: 1024,
+			},
+			wantFound: true,
+		},
+		{name: "not an integer", header: http.Header{HeaderLatencyMS: {"1.5"}}, wantErr: true},
+		{name: "not a number", header: http.Header{HeaderErrorPercent: {"half"}}, wantErr: true},
+		{name: "out of range", header: http.Header{HeaderSlowBodyBPS: {"9999999999"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, found, err := FromHeaders("GET /api/quote/random", tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromHeaders() error = %v, want error %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound || rule != tt.want {
+				t.Errorf("FromHeaders() = %+v, %v, want %+v, %v", rule, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestInjector(t *testing.T) {
+	inj := NewInjector()
+	byID := Rule{Route: "GET /api/quote/{id}", ErrorPercent: 10}
+	fallback := Rule{Route: AnyRoute, LatencyMS: 5}
+
+	if err := inj.SetRules([]Rule{fallback, byID}); err != nil {
+		t.Fatal(err)
+	}
+	if rule, ok := inj.Match("GET /api/quote/{id}"); !ok || rule != byID {
+		t.Errorf("Match(route with a rule) = %+v, %v", rule, ok)
+	}
+	if rule, ok := inj.Match("GET /api/version"); !ok || rule != fallback {
+		t.Errorf("Match(other route) = %+v, %v, want the %q rule", rule, ok, AnyRoute)
+	}
+	if rules := inj.Rules(); len(rules) != 2 || rules[0].Route != AnyRoute {
+		t.Errorf("Rules() = %+v, want them sorted by route", rules)
+	}
+
+	// Invalid rule sets change nothing
+	if err := inj.SetRules([]Rule{byID, byID}); !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("SetRules(duplicates) error = %v, want ErrDuplicateRoute", err)
+	}
+	if err := inj.SetRules([]Rule{{Route: AnyRoute, ErrorStatus: 200}}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("SetRules(invalid) error = %v, want ErrInvalidRule", err)
+	}
+	if rules := inj.Rules(); len(rules) != 2 {
+		t.Errorf("Rules() after invalid updates = %+v", rules)
+	}
+
+	inj.Clear()
+	if _, ok := inj.Match("GET /api/version"); ok {
+		t.Error("Match() found a rule after Clear()")
+	}
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+var (
+	errNotStarted = errors.New("startup not completed")
+	errDraining   = errors.New("server is draining")
+)
+
+// CheckFunc reports whether a dependency is usable. It should respect ctx cancellation.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of all readiness checks. Status is "fail" if any critical check failed.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Checker tracks the lifecycle state of the service and runs the registered dependency checks
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// NewChecker creates a Checker that gives each dependency check at most timeout to complete
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// AddCheck registers a dependency check. Failing critical checks make the service not ready,
+// failing informational checks are only reported. Must be called before the checker is in use.
+func (c *Checker) AddCheck(name string, critical bool, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// MarkStarted records that startup has completed
+func (c *Checker) MarkStarted() {
+	c.started.Store(true)
+}
+
+// StartDraining makes the service report not ready so it is taken out of rotation
+func (c *Checker) StartDraining() {
+	c.draining.Store(true)
+}
+
+// Readiness runs all checks concurrently and reports whether the service can take traffic
+func (c *Checker) Readiness(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks)+2) //nolint:mnd
+	results[0] = stateResult("startup", c.started.Load(), errNotStarted)
+	results[1] = stateResult("draining", !c.draining.Load(), errDraining)
+
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i+2] = c.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Critical && result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, chk check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := chk.fn(ctx)
+
+	result := CheckResult{
+		Name:       chk.name,
+		Status:     StatusOK,
+		Critical:   chk.critical,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func stateResult(name string, ok bool, err error) CheckResult {
+	if ok {
+		return CheckResult{Name: name, Status: StatusOK, Critical: true}
+	}
+	return CheckResult{Name: name, Status: StatusFail, Critical: true, Error: err.Error()}
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// statuses returns the status of every check in r by name
+func statuses(r Report) map[string]string {
+	m := make(map[string]string, len(r.Checks))
+	for _, c := range r.Checks {
+		m[c.Name] = c.Status
+	}
+	return m
+}
+
+func TestReadinessLifecycle(t *testing.T) {
+	c := NewChecker(time.Second)
+	ctx := context.Background()
+
+	if report := c.Readiness(ctx); report.Status != StatusFail || statuses(report)["startup"] != StatusFail {
+		t.Errorf("before MarkStarted: %+v, want the startup check to fail", report)
+	}
+
+	c.MarkStarted()
+	if report := c.Readiness(ctx); report.Status != StatusOK {
+		t.Errorf("after MarkStarted: %+v, want ok", report)
+	}
+
+	c.StartDraining()
+	report := c.Readiness(ctx)
+	if report.Status != StatusFail || statuses(report)["draining"] != StatusFail {
+		t.Errorf("while draining: %+v, want the draining check to fail", report)
+	}
+	if report.Checks[1].Error != errDraining.Error() {
+		t.Errorf("draining error = %q, want %q", report.Checks[1].Error, errDraining)
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	passing := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		critical   bool
+		fn         CheckFunc
+		wantStatus string
+	}{
+		{name: "critical passing", critical: true, fn: passing, wantStatus: StatusOK},
+		{name: "critical failing", critical: true, fn: failing, wantStatus: StatusFail},
+		{name: "informational failing", critical: false, fn: failing, wantStatus: StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(time.Second)
+			c.MarkStarted()
+			c.AddCheck("dependency", tt.critical, tt.fn)
+
+			report := c.Readiness(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != 3 {
+				t.Fatalf("got %d checks, want 3", len(report.Checks))
+			}
+			result := report.Checks[2]
+			if result.Name != "dependency" || result.Critical != tt.critical {
+				t.Errorf("result = %+v", result)
+			}
+			if (result.Error != "") != (result.Status == StatusFail) {
+				t.Errorf("result = %+v, want an error exactly when the check failed", result)
+			}
+		})
+	}
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	c := NewChecker(10 * time.Millisecond)
+	c.MarkStarted()
+	c.AddCheck("slow", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := c.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Readiness took %v, want the check to be cut off after its timeout", elapsed)
+	}
+	if report.Status != StatusFail || report.Checks[2].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("report = %+v, want the slow check to fail with a deadline error", report)
+	}
+}
@@ -0,0 +1,142 @@
+package memstats
+
+import (
+	"bufio"
+	"io/fs"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Paths are relative to the root of the file system passed to readRSS and readCgroup
+const (
+	procStatusFile = "proc/self/status"
+
+	cgroupV2LimitFile = "sys/fs/cgroup/memory.max"
+	cgroupV2UsageFile = "sys/fs/cgroup/memory.current"
+	cgroupV1LimitFile = "sys/fs/cgroup/memory/memory.limit_in_bytes"
+	cgroupV1UsageFile = "sys/fs/cgroup/memory/memory.usage_in_bytes"
+
+	// cgroupV1Unlimited is the lower bound of the limit cgroup v1 reports when there is none
+	// (the max int64 rounded down to the page size)
+	cgroupV1Unlimited = math.MaxInt64 / 2
+
+	bytesPerMB = 1024 * 1024
+)
+
+// Stats is a snapshot of the memory held by the process. Values that can't be read on the current
+// platform are left nil.
+type Stats struct {
+	// HeapInuseMB and HeapSysMB come from the Go runtime
+	HeapInuseMB float64 `json:"heap_inuse_mb"`
+	HeapSysMB   float64 `json:"heap_sys_mb"`
+	// RSSMB is the resident set size from /proc/self/status
+	RSSMB  *float64 `json:"rss_mb,omitempty"`
+	Cgroup *Cgroup  `json:"cgroup,omitempty"`
+}
+
+// Cgroup is the memory limit and usage of the cgroup the process runs in, i.e. the pod limit
+// under Kubernetes
+type Cgroup struct {
+	Version int `json:"version"`
+	// LimitMB and UsagePercent are nil when the cgroup has no memory limit
+	LimitMB      *float64 `json:"limit_mb,omitempty"`
+	UsageMB      float64  `json:"usage_mb"`
+	UsagePercent *float64 `json:"usage_percent,omitempty"`
+}
+
+// Read takes a snapshot of the memory stats. It stops the world briefly to read the runtime stats.
+func Read() Stats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	root := os.DirFS("/")
+	stats := Stats{
+		HeapInuseMB: toMB(ms.HeapInuse),
+		HeapSysMB:   toMB(ms.HeapSys),
+		Cgroup:      readCgroup(root),
+	}
+	if rss, ok := readRSS(root); ok {
+		stats.RSSMB = &rss
+	}
+
+	return stats
+}
+
+// readRSS reads VmRSS, reported in kB, from /proc/self/status
+func readRSS(root fs.FS) (float64, bool) {
+	file, err := root.Open(procStatusFile)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !found {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return toMB(kb * 1024), true
+	}
+	return 0, false
+}
+
+// readCgroup reads the memory limit and usage from the cgroup v2 files, falling back to cgroup v1
+func readCgroup(root fs.FS) *Cgroup {
+	if usage, ok := readUint(root, cgroupV2UsageFile); ok {
+		cgroup := &Cgroup{Version: 2, UsageMB: toMB(usage)}
+		// memory.max is "max" without a limit
+		if limit, ok := readUint(root, cgroupV2LimitFile); ok {
+			cgroup.setLimit(limit)
+		}
+		return cgroup
+	}
+
+	if usage, ok := readUint(root, cgroupV1UsageFile); ok {
+		cgroup := &Cgroup{Version: 1, UsageMB: toMB(usage)}
+		if limit, ok := readUint(root, cgroupV1LimitFile); ok && limit < cgroupV1Unlimited {
+			cgroup.setLimit(limit)
+		}
+		return cgroup
+	}
+
+	return nil
+}
+
+func (c *Cgroup) setLimit(limit uint64) {
+	limitMB := toMB(limit)
+	usagePercent := round(c.UsageMB / limitMB * 100)
+	c.LimitMB = &limitMB
+	c.UsagePercent = &usagePercent
+}
+
+// readUint reads a file holding a single unsigned integer
+func readUint(root fs.FS, path string) (uint64, bool) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// toMB converts bytes to MB, rounded to one decimal
+func toMB(bytes uint64) float64 {
+	return round(float64(bytes) / bytesPerMB)
+}
+
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,107 @@
+package memstats
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadRSS(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   float64
+		wantOK bool
+	}{
+		{name: "VmRSS", status: "Name:\tquote-service\nVmPeak:\t  900000 kB\nVmRSS:\t   51200 kB\nThreads:\t8\n", want: 50, wantOK: true},
+		{name: "no VmRSS", status: "Name:\tquote-service\n"},
+		{name: "malformed VmRSS", status: "VmRSS:\t50 MB\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := fstest.MapFS{procStatusFile: {Data: []byte(tt.status)}}
+			rss, ok := readRSS(root)
+			if rss != tt.want || ok != tt.wantOK {
+				t.Errorf("readRSS() = %v, %v, want %v, %v", rss, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := readRSS(fstest.MapFS{}); ok {
+		t.Error("readRSS() succeeded without /proc")
+	}
+}
+
+func TestReadCgroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantVersion  int
+		wantUsage    float64
+		wantLimit    float64
+		wantPercent  float64
+		wantNoLimit  bool
+		wantNoCgroup bool
+	}{
+		{
+			name:        "v2 with a limit",
+			files:       map[string]string{cgroupV2UsageFile: "134217728\n", cgroupV2LimitFile: "536870912\n"},
+			wantVersion: 2, wantUsage: 128, wantLimit: 512, wantPercent: 25,
+		},
+		{
+			name:        "v2 without a limit",
+			files:       map[string]string{cgroupV2UsageFile: "134217728\n", cgroupV2LimitFile: "max\n"},
+			wantVersion: 2, wantUsage: 128, wantNoLimit: true,
+		},
+		{
+			name:        "v1 with a limit",
+			files:       map[string]string{cgroupV1UsageFile: "104857600\n", cgroupV1LimitFile: "314572800\n"},
+			wantVersion: 1, wantUsage: 100, wantLimit: 300, wantPercent: 33.3,
+		},
+		{
+			name:        "v1 without a limit",
+			files:       map[string]string{cgroupV1UsageFile: "104857600\n", cgroupV1LimitFile: "9223372036854771712\n"},
+			wantVersion: 1, wantUsage: 100, wantNoLimit: true,
+		},
+		{name: "no cgroup", files: map[string]string{}, wantNoCgroup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := fstest.MapFS{}
+			for path, data := range tt.files {
+				root[path] = &fstest.MapFile{Data: []byte(data)}
+			}
+
+			cgroup := readCgroup(root)
+			if tt.wantNoCgroup {
+				if cgroup != nil {
+					t.Errorf("readCgroup() = %+v, want nil", cgroup)
+				}
+				return
+			}
+			if cgroup == nil {
+				t.Fatal("readCgroup() = nil")
+			}
+			if cgroup.Version != tt.wantVersion || cgroup.UsageMB != tt.wantUsage {
+				t.Errorf("readCgroup() = version %d, %v MB used, want version %d, %v MB", cgroup.Version, cgroup.UsageMB, tt.wantVersion, tt.wantUsage)
+			}
+			if tt.wantNoLimit {
+				if cgroup.LimitMB != nil || cgroup.UsagePercent != nil {
+					t.Errorf("readCgroup() has a limit of %v MB, want none", *cgroup.LimitMB)
+				}
+				return
+			}
+			if cgroup.LimitMB == nil || *cgroup.LimitMB != tt.wantLimit || *cgroup.UsagePercent != tt.wantPercent {
+				t.Errorf("readCgroup() limit = %v MB at %v%%, want %v MB at %v%%", cgroup.LimitMB, cgroup.UsagePercent, tt.wantLimit, tt.wantPercent)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	stats := Read()
+	if stats.HeapInuseMB <= 0 || stats.HeapSysMB < stats.HeapInuseMB {
+		t.Errorf("Read() = %+v, want the heap in use within the heap", stats)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "quote_service"
+
+// Metrics holds the Prometheus collectors of the service. All collectors are registered on a
+// dedicated registry which is exposed by Handler.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequestsTotal   *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+
+	authorServiceRequestDuration *prometheus.HistogramVec
+	authorServiceErrorsTotal     *prometheus.CounterVec
+
+	repositoryOperationDuration *prometheus.HistogramVec
+}
+
+// New creates the service metrics together with the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status code.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"route", "method", "code"}),
+
+		authorServiceRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "author_service_request_duration_seconds",
+			Help:      "Latency of requests to the author-service by path and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"path", "code"}),
+		authorServiceErrorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "author_service_errors_total",
+			Help:      "Failed requests to the author-service by path and reason (timeout, network or HTTP status code).",
+		}, []string{"path", "reason"}),
+
+		repositoryOperationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Latency of repository operations by operation and result.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequestsTotal,
+		m.httpRequestDuration,
+		m.authorServiceRequestDuration,
+		m.authorServiceErrorsTotal,
+		m.repositoryOperationDuration,
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterGaugeFunc registers a gauge whose value is read from fn on every scrape
+func (m *Metrics) RegisterGaugeFunc(name, help string, fn func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// ObserveHTTPRequest records a served HTTP request
+func (m *Metrics) ObserveHTTPRequest(route, method string, statusCode int, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	m.httpRequestsTotal.WithLabelValues(route, method, code).Inc()
+	m.httpRequestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveRepositoryOperation records a repository call. result is a short outcome such as
+// "ok", "not_found" or "error".
+func (m *Metrics) ObserveRepositoryOperation(operation, result string, duration time.Duration) {
+	m.repositoryOperationDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
+
+// InstrumentAuthorServiceTransport wraps next so requests to the author-service are measured
+func (m *Metrics) InstrumentAuthorServiceTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(r)
+		duration := time.Since(start)
+
+		path := r.URL.Path
+		if err != nil {
+			m.authorServiceRequestDuration.WithLabelValues(path, "error").Observe(duration.Seconds())
+			m.authorServiceErrorsTotal.WithLabelValues(path, errorReason(err)).Inc()
+			return nil, err
+		}
+
+		code := strconv.Itoa(resp.StatusCode)
+		m.authorServiceRequestDuration.WithLabelValues(path, code).Observe(duration.Seconds())
+		if resp.StatusCode >= http.StatusBadRequest {
+			m.authorServiceErrorsTotal.WithLabelValues(path, code).Inc()
+		}
+
+		return resp, nil
+	})
+}
+
+// errorReason classifies a transport error for the errors counter
+func errorReason(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	return "network"
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
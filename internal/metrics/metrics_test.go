@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the metrics exposed by m in the text exposition format
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics don't contain %q", line)
+		}
+	}
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest("GET /api/quote/{id}", http.MethodGet, http.StatusOK, 20*time.Millisecond)
+	m.ObserveHTTPRequest("GET /api/quote/{id}", http.MethodGet, http.StatusOK, 20*time.Millisecond)
+	m.ObserveHTTPRequest("GET /api/quote/{id}", http.MethodGet, http.StatusNotFound, time.Millisecond)
+
+	assertContains(t, scrape(t, m),
+		`quote_service_http_requests_total{code="200",method="GET",route="GET /api/quote/{id}"} 2`,
+		`quote_service_http_requests_total{code="404",method="GET",route="GET /api/quote/{id}"} 1`,
+		`quote_service_http_request_duration_seconds_bucket{code="200",method="GET",route="GET /api/quote/{id}",le="0.025"} 2`,
+		`go_goroutines`,
+	)
+}
+
+func TestObserveRepositoryOperation(t *testing.T) {
+	m := New()
+	m.ObserveRepositoryOperation("GetQuoteByID", "not_found", time.Millisecond)
+
+	assertContains(t, scrape(t, m),
+		`quote_service_repository_operation_duration_seconds_count{operation="GetQuoteByID",result="not_found"} 1`)
+}
+
+func TestRegisterGaugeFunc(t *testing.T) {
+	m := New()
+	m.RegisterGaugeFunc("mock_memory_allocations", "Active allocations.", func() float64 { return 3 })
+
+	assertContains(t, scrape(t, m), `quote_service_mock_memory_allocations 3`)
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestInstrumentAuthorServiceTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   *http.Response
+		err        error
+		wantLines  []string
+		wantErrors bool
+	}{
+		{
+			name:     "success",
+			response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))},
+			wantLines: []string{
+				`quote_service_author_service_request_duration_seconds_count{code="200",path="/authors/1"} 1`,
+			},
+		},
+		{
+			name:     "server error",
+			response: &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader(""))},
+			wantLines: []string{
+				`quote_service_author_service_request_duration_seconds_count{code="502",path="/authors/1"} 1`,
+				`quote_service_author_service_errors_total{path="/authors/1",reason="502"} 1`,
+			},
+			wantErrors: true,
+		},
+		{
+			name: "timeout",
+			err:  timeoutError{},
+			wantLines: []string{
+				`quote_service_author_service_request_duration_seconds_count{code="error",path="/authors/1"} 1`,
+				`quote_service_author_service_errors_total{path="/authors/1",reason="timeout"} 1`,
+			},
+			wantErrors: true,
+		},
+		{
+			name: "network",
+			err:  errors.New("connection refused"),
+			wantLines: []string{
+				`quote_service_author_service_errors_total{path="/authors/1",reason="network"} 1`,
+			},
+			wantErrors: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			transport := m.InstrumentAuthorServiceTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return tt.response, tt.err
+			}))
+
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://author-service/authors/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("RoundTrip() error = %v, want %v", err, tt.err)
+			}
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+
+			body := scrape(t, m)
+			assertContains(t, body, tt.wantLines...)
+			if got := strings.Contains(body, "quote_service_author_service_errors_total{"); got != tt.wantErrors {
+				t.Errorf("errors counter present = %v, want %v", got, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter whose clock only moves when the returned function is called
+func newTestLimiter(limit Limit) (*Limiter, func(time.Duration)) {
+	l := NewLimiter(limit)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l, advance := newTestLimiter(Limit{Rate: 2, Burst: 3})
+
+	for i := range 3 {
+		result := l.Allow("client")
+		if !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("request %d: %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	result := l.Allow("client")
+	if result.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	if result.RetryAfter != 500*time.Millisecond || result.Reset != 1500*time.Millisecond {
+		t.Errorf("RetryAfter = %v, Reset = %v, want 500ms and 1.5s", result.RetryAfter, result.Reset)
+	}
+
+	// Other keys have their own bucket
+	if !l.Allow("other").Allowed {
+		t.Error("other client was limited")
+	}
+
+	advance(500 * time.Millisecond)
+	if !l.Allow("client").Allowed {
+		t.Error("request after the refill was limited")
+	}
+}
+
+func TestLimiterPeek(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 1, Burst: 1})
+
+	for range 2 {
+		if result := l.Peek("client"); !result.Allowed || result.Remaining != 1 {
+			t.Fatalf("Peek() = %+v, want allowed without taking the token", result)
+		}
+	}
+	l.Allow("client")
+	if l.Peek("client").Allowed {
+		t.Error("Peek() allowed with an empty bucket")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l, advance := newTestLimiter(Limit{Rate: 1, Burst: 1})
+	l.Allow("idle")
+
+	advance(sweepInterval)
+	l.Allow("active")
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("refilled bucket wasn't swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
+
+func TestLimitValidate(t *testing.T) {
+	tests := []struct {
+		limit   Limit
+		wantErr bool
+	}{
+		{limit: Limit{Rate: 0.1, Burst: 1}},
+		{limit: Limit{Rate: 0, Burst: 10}, wantErr: true},
+		{limit: Limit{Rate: -1, Burst: 10}, wantErr: true},
+		{limit: Limit{Rate: math.NaN(), Burst: 10}, wantErr: true},
+		{limit: Limit{Rate: math.Inf(1), Burst: 10}, wantErr: true},
+		{limit: Limit{Rate: 10, Burst: 0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.limit.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() error = %v, want error %v", tt.limit, err, tt.wantErr)
+		}
+	}
+}
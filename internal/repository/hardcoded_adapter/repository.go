@@ -1,13 +1,15 @@
 package hardcodedrepository
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"math/rand"
 	"quote-service/internal/repository"
 )
 
 var (
-	ErrNotFound = errors.New("quote not found")
+	// ErrNotFound wraps repository.ErrNotFound so callers can check for it with errors.Is
+	ErrNotFound = fmt.Errorf("quote %w", repository.ErrNotFound)
 )
 
 type HardcodedRepository struct {
@@ -45,7 +47,7 @@ func NewHardcodedRepository() *HardcodedRepository {
 }
 
 // GetQuoteByID returns a quote by its ID
-func (r *HardcodedRepository) GetQuoteByID(id int) (*repository.Quote, error) {
+func (r *HardcodedRepository) GetQuoteByID(_ context.Context, id int) (*repository.Quote, error) {
 	quote, ok := r.quotes[id]
 	if !ok {
 		return nil, ErrNotFound
@@ -55,7 +57,7 @@ func (r *HardcodedRepository) GetQuoteByID(id int) (*repository.Quote, error) {
 }
 
 // GetRandomQuote returns a random quote from the collection
-func (r *HardcodedRepository) GetRandomQuote() (*repository.Quote, error) {
+func (r *HardcodedRepository) GetRandomQuote(_ context.Context) (*repository.Quote, error) {
 	if len(r.quotes) == 0 {
 		return nil, ErrNotFound
 	}
@@ -66,3 +68,8 @@ func (r *HardcodedRepository) GetRandomQuote() (*repository.Quote, error) {
 
 	return &quote, nil
 }
+
+// Ping implements repository.Repository. The quotes are held in memory so it never fails.
+func (r *HardcodedRepository) Ping(_ context.Context) error {
+	return nil
+}
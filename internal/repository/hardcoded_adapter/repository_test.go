@@ -0,0 +1,36 @@
+package hardcodedrepository
+
+import (
+	"context"
+	"errors"
+	"quote-service/internal/repository"
+	"testing"
+)
+
+func TestHardcodedRepository(t *testing.T) {
+	repo := NewHardcodedRepository()
+	ctx := context.Background()
+
+	quote, err := repo.GetQuoteByID(ctx, 3)
+	if err != nil || quote.ID != 3 || quote.AuthorID != 3 || quote.Message == "" {
+		t.Errorf("GetQuoteByID(3) = %+v, %v", quote, err)
+	}
+
+	if _, err := repo.GetQuoteByID(ctx, 999); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetQuoteByID(999) error = %v, want repository.ErrNotFound", err)
+	}
+
+	for range 20 {
+		quote, err := repo.GetRandomQuote(ctx)
+		if err != nil {
+			t.Fatalf("GetRandomQuote() error = %v", err)
+		}
+		if _, err := repo.GetQuoteByID(ctx, quote.ID); err != nil {
+			t.Errorf("GetRandomQuote() returned unknown quote %d", quote.ID)
+		}
+	}
+
+	if err := repo.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package metricsrepository
+
+import (
+	"context"
+	"errors"
+	"quote-service/internal/metrics"
+	"quote-service/internal/repository"
+	"time"
+)
+
+// MetricsRepository wraps another repository.Repository and records the latency of every call
+type MetricsRepository struct {
+	next    repository.Repository
+	metrics *metrics.Metrics
+}
+
+var _ repository.Repository = (*MetricsRepository)(nil)
+
+// NewMetricsRepository creates a repository that measures calls to next
+func NewMetricsRepository(next repository.Repository, m *metrics.Metrics) *MetricsRepository {
+	return &MetricsRepository{
+		next:    next,
+		metrics: m,
+	}
+}
+
+// GetQuoteByID implements repository.Repository.
+func (r *MetricsRepository) GetQuoteByID(ctx context.Context, id int) (*repository.Quote, error) {
+	start := time.Now()
+	quote, err := r.next.GetQuoteByID(ctx, id)
+	r.metrics.ObserveRepositoryOperation("GetQuoteByID", result(err), time.Since(start))
+
+	return quote, err
+}
+
+// GetRandomQuote implements repository.Repository.
+func (r *MetricsRepository) GetRandomQuote(ctx context.Context) (*repository.Quote, error) {
+	start := time.Now()
+	quote, err := r.next.GetRandomQuote(ctx)
+	r.metrics.ObserveRepositoryOperation("GetRandomQuote", result(err), time.Since(start))
+
+	return quote, err
+}
+
+// Ping implements repository.Repository.
+func (r *MetricsRepository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.Ping(ctx)
+	r.metrics.ObserveRepositoryOperation("Ping", result(err), time.Since(start))
+
+	return err
+}
+
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, repository.ErrNotFound):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
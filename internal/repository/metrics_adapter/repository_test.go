@@ -0,0 +1,60 @@
+package metricsrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"quote-service/internal/metrics"
+	"quote-service/internal/repository"
+	"strings"
+	"testing"
+)
+
+// fakeRepository returns quote or err from every call
+type fakeRepository struct {
+	quote *repository.Quote
+	err   error
+}
+
+func (f fakeRepository) GetQuoteByID(context.Context, int) (*repository.Quote, error) {
+	return f.quote, f.err
+}
+
+func (f fakeRepository) GetRandomQuote(context.Context) (*repository.Quote, error) {
+	return f.quote, f.err
+}
+
+func (f fakeRepository) Ping(context.Context) error { return f.err }
+
+func TestMetricsRepository(t *testing.T) {
+	tests := []struct {
+		name       string
+		next       fakeRepository
+		wantResult string
+	}{
+		{name: "found", next: fakeRepository{quote: &repository.Quote{ID: 7}}, wantResult: "ok"},
+		{name: "not found", next: fakeRepository{err: fmt.Errorf("quote 7: %w", repository.ErrNotFound)}, wantResult: "not_found"},
+		{name: "failure", next: fakeRepository{err: errors.New("connection refused")}, wantResult: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.New()
+			repo := NewMetricsRepository(tt.next, m)
+
+			quote, err := repo.GetQuoteByID(context.Background(), 7)
+			if quote != tt.next.quote || !errors.Is(err, tt.next.err) {
+				t.Errorf("GetQuoteByID() = %v, %v, want the result of the wrapped repository", quote, err)
+			}
+
+			w := httptest.NewRecorder()
+			m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			want := fmt.Sprintf(`quote_service_repository_operation_duration_seconds_count{operation="GetQuoteByID",result=%q} 1`, tt.wantResult)
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("metrics don't contain %q", want)
+			}
+		})
+	}
+}
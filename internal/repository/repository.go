@@ -1,5 +1,7 @@
 package repository
 
+import "context"
+
 type Quote struct {
 	ID       int
 	Message  string
@@ -7,6 +9,8 @@ type Quote struct {
 }
 
 type Repository interface {
-	GetQuoteByID(id int) (*Quote, error)
-	GetRandomQuote() (*Quote, error)
+	GetQuoteByID(ctx context.Context, id int) (*Quote, error)
+	GetRandomQuote(ctx context.Context) (*Quote, error)
+	// Ping reports whether the underlying storage is reachable
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,83 @@
+package tracedrepository
+
+import (
+	"context"
+	"errors"
+	"quote-service/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "quote-service/internal/repository"
+
+// TracedRepository wraps another repository.Repository and records a span for every call
+type TracedRepository struct {
+	next   repository.Repository
+	tracer trace.Tracer
+}
+
+var _ repository.Repository = (*TracedRepository)(nil)
+
+// NewTracedRepository creates a repository that traces calls to next using the global tracer provider
+func NewTracedRepository(next repository.Repository) *TracedRepository {
+	return &TracedRepository{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// GetQuoteByID implements repository.Repository.
+func (r *TracedRepository) GetQuoteByID(ctx context.Context, id int) (*repository.Quote, error) {
+	ctx, span := r.tracer.Start(ctx, "repository.GetQuoteByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.Int("quote.id", id)))
+	defer span.End()
+
+	quote, err := r.next.GetQuoteByID(ctx, id)
+	recordError(span, err)
+
+	return quote, err
+}
+
+// GetRandomQuote implements repository.Repository.
+func (r *TracedRepository) GetRandomQuote(ctx context.Context) (*repository.Quote, error) {
+	ctx, span := r.tracer.Start(ctx, "repository.GetRandomQuote", trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	quote, err := r.next.GetRandomQuote(ctx)
+	if quote != nil {
+		span.SetAttributes(attribute.Int("quote.id", quote.ID))
+	}
+	recordError(span, err)
+
+	return quote, err
+}
+
+// Ping implements repository.Repository.
+func (r *TracedRepository) Ping(ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "repository.Ping", trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	err := r.next.Ping(ctx)
+	recordError(span, err)
+
+	return err
+}
+
+// recordError marks the span as failed. Not found results are expected and only recorded as an attribute.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, repository.ErrNotFound) {
+		span.SetAttributes(attribute.Bool("quote.not_found", true))
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,102 @@
+package tracedrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quote-service/internal/repository"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeRepository returns quote or err from every call
+type fakeRepository struct {
+	quote *repository.Quote
+	err   error
+}
+
+func (f fakeRepository) GetQuoteByID(context.Context, int) (*repository.Quote, error) {
+	return f.quote, f.err
+}
+
+func (f fakeRepository) GetRandomQuote(context.Context) (*repository.Quote, error) {
+	return f.quote, f.err
+}
+
+func (f fakeRepository) Ping(context.Context) error { return f.err }
+
+func TestTracedRepository(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tests := []struct {
+		name         string
+		next         fakeRepository
+		wantStatus   codes.Code
+		wantNotFound bool
+	}{
+		{name: "found", next: fakeRepository{quote: &repository.Quote{ID: 7}}, wantStatus: codes.Unset},
+		{
+			name:         "not found",
+			next:         fakeRepository{err: fmt.Errorf("quote 7: %w", repository.ErrNotFound)},
+			wantStatus:   codes.Unset,
+			wantNotFound: true,
+		},
+		{name: "failure", next: fakeRepository{err: errors.New("connection refused")}, wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.Reset()
+			repo := NewTracedRepository(tt.next)
+
+			_, _ = repo.GetQuoteByID(context.Background(), 7)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != "repository.GetQuoteByID" {
+				t.Errorf("span name = %q", span.Name())
+			}
+			if got := span.Status().Code; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+			attrs := attribute.NewSet(span.Attributes()...)
+			if got, _ := attrs.Value("quote.id"); got.AsInt64() != 7 {
+				t.Errorf("quote.id = %v, want 7", got.Emit())
+			}
+			if got, _ := attrs.Value("quote.not_found"); got.AsBool() != tt.wantNotFound {
+				t.Errorf("quote.not_found = %v, want %v", got.Emit(), tt.wantNotFound)
+			}
+			if wantEvents := tt.wantStatus == codes.Error; (len(span.Events()) > 0) != wantEvents {
+				t.Errorf("got %d error events, want recorded = %v", len(span.Events()), wantEvents)
+			}
+		})
+	}
+}
+
+func TestTracedRepositoryRandomQuoteID(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	repo := NewTracedRepository(fakeRepository{quote: &repository.Quote{ID: 3}})
+
+	if _, err := repo.GetRandomQuote(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	attrs := attribute.NewSet(spans[0].Attributes()...)
+	if got, _ := attrs.Value("quote.id"); got.AsInt64() != 3 {
+		t.Errorf("quote.id = %v, want 3", got.Emit())
+	}
+}
@@ -0,0 +1,142 @@
+package restapi
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AccessLogFields lists the fields an access log line can have. The trace ID is always added by
+// the logger.
+var AccessLogFields = []string{
+	"method", "route", "path", "status", "bytes", "duration_ms", "client_ip", "user_agent", "referer", "proto",
+}
+
+type AccessLogConfig struct {
+	Enabled bool
+	// SampleRate is the fraction of successful requests that are logged, between 0 and 1. Errors
+	// and slow requests are always logged.
+	SampleRate float64
+	// SlowThreshold is the duration above which a request is always logged, zero to disable
+	SlowThreshold time.Duration
+	// ExcludedPaths are never logged, e.g. probes
+	ExcludedPaths []string
+	// Fields selects which of AccessLogFields are logged, all of them when empty
+	Fields []string
+}
+
+func (c AccessLogConfig) validate() error {
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("access log sample rate must be between 0 and 1, got %v", c.SampleRate)
+	}
+	for _, name := range c.Fields {
+		if !slices.Contains(AccessLogFields, name) {
+			return fmt.Errorf("unknown access log field %q, must be one of %s", name, strings.Join(AccessLogFields, ", "))
+		}
+	}
+	return nil
+}
+
+// accessLogMiddleware logs a line per request through the "access" logger. It must run inside
+// metricsMiddleware, which provides the route, and traceIDMiddleware, which provides the trace ID.
+// Requests whose handler panics, including dropped connections injected as faults, are logged as
+// errors with "panic" set before the panic continues to the server. Their status is 0 if no
+// response was sent.
+func (a *App) accessLogMiddleware(next http.Handler) http.Handler {
+	if !a.AccessLog.Enabled {
+		return next
+	}
+
+	accessLogger := a.Logger.Named("access")
+	fields := a.AccessLog.Fields
+	if len(fields) == 0 {
+		fields = AccessLogFields
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(a.AccessLog.ExcludedPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		defer func() {
+			recovered := recover()
+			a.logRequest(accessLogger, fields, r, rec, time.Since(start), recovered)
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// logRequest writes the access log line of a finished request. recovered is the value of a
+// handler panic, nil if the handler returned.
+func (a *App) logRequest(accessLogger logger.Logger, fields []string, r *http.Request, rec *responseRecorder, duration time.Duration, recovered any) {
+	statusCode := rec.statusCode
+	if recovered != nil && !rec.wroteHeader {
+		statusCode = 0
+	}
+	isError := recovered != nil || statusCode >= http.StatusBadRequest
+	isSlow := a.AccessLog.SlowThreshold > 0 && duration >= a.AccessLog.SlowThreshold
+	if !isError && !isSlow && rand.Float64() >= a.AccessLog.SampleRate {
+		return
+	}
+
+	route := unmatchedRoute
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		route = info.route
+	}
+
+	logFields := make([]field.Field, 0, len(fields)+2)
+	for _, name := range fields {
+		switch name {
+		case "method":
+			logFields = append(logFields, field.String(name, r.Method))
+		case "route":
+			logFields = append(logFields, field.String(name, route))
+		case "path":
+			logFields = append(logFields, field.String(name, r.URL.Path))
+		case "status":
+			logFields = append(logFields, field.Int(name, statusCode))
+		case "bytes":
+			logFields = append(logFields, field.Int(name, rec.bytes))
+		case "duration_ms":
+			logFields = append(logFields, field.Float64(name, float64(duration.Microseconds())/1000))
+		case "client_ip":
+			logFields = append(logFields, field.String(name, a.clientIP(r)))
+		case "user_agent":
+			logFields = append(logFields, field.String(name, r.UserAgent()))
+		case "referer":
+			logFields = append(logFields, field.String(name, r.Referer()))
+		case "proto":
+			logFields = append(logFields, field.String(name, r.Proto))
+		}
+	}
+	if isSlow {
+		logFields = append(logFields, field.Bool("slow", true))
+	}
+	if recovered == http.ErrAbortHandler {
+		logFields = append(logFields, field.String("panic", "connection aborted"))
+	} else if recovered != nil {
+		logFields = append(logFields, field.String("panic", fmt.Sprint(recovered)))
+	}
+
+	switch {
+	case recovered != nil || statusCode >= http.StatusInternalServerError:
+		accessLogger.ErrorFields(r.Context(), "HTTP request", logFields...)
+	case isError || isSlow:
+		accessLogger.WarnFields(r.Context(), "HTTP request", logFields...)
+	default:
+		accessLogger.InfoFields(r.Context(), "HTTP request", logFields...)
+	}
+}
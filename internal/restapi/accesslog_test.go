@@ -0,0 +1,61 @@
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+)
+
+func TestAccessLogPanickingRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantPanic string
+		wantCode  int64
+	}{
+		{
+			name:      "dropped connection",
+			handler:   func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) },
+			wantPanic: "connection aborted",
+			wantCode:  0,
+		},
+		{
+			name: "panic after the header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("boom")
+			},
+			wantPanic: "boom",
+			wantCode:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := testlogger.New()
+			a := &App{Logger: log, AccessLog: AccessLogConfig{Enabled: true, SampleRate: 1}}
+			handler := a.accessLogMiddleware(tt.handler)
+
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("the panic wasn't passed on to the server")
+					}
+				}()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/quote/random", nil))
+			}()
+
+			entry, ok := log.Find(testlogger.LevelError, "HTTP request")
+			if !ok {
+				t.Fatalf("no access log error in:\n%s", log)
+			}
+			if got := entry.String("panic"); got != tt.wantPanic {
+				t.Errorf("panic = %q, want %q", got, tt.wantPanic)
+			}
+			if got, _ := entry.Attr("status"); got != tt.wantCode {
+				t.Errorf("status = %v, want %d", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,63 +1,272 @@
 package restapi
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"quote-service/internal/auth"
+	"quote-service/internal/faults"
+	"quote-service/internal/health"
+	"quote-service/internal/metrics"
 	"quote-service/internal/repository"
 	"quote-service/internal/restapi/routes"
 	"quote-service/pkg/authorclient"
 	"quote-service/pkg/logger"
 	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type App struct {
-	Version      string
-	Logger       logger.Logger
+	Version string
+	Logger  logger.Logger
+	// LogLevels changes log levels at runtime through the admin API, nil to disable it
+	LogLevels    logger.LevelController
 	Repository   repository.Repository
 	AuthorClient *authorclient.Client
+	Metrics      *metrics.Metrics
+	Health       *health.Checker
+	Auth         *auth.Authenticator
+	// AuthDisabled opens admin routes when no credentials are configured. Without it they are
+	// denied, so a missing key configuration doesn't expose them.
+	AuthDisabled bool
+	// MemoryBudget limits the memory held by mock-memory requests, nil for no limit
+	MemoryBudget *routes.MemoryBudget
+	// MaxMemoryJobs caps the queued and running async mock-memory jobs, 0 for no limit
+	MaxMemoryJobs int
 
 	Port int
 	Host string
+
+	// Server limits. Zero values mean no limit, except MaxHeaderBytes which falls back to
+	// http.DefaultMaxHeaderBytes.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	TLS       TLSConfig
+	CORS      CORSConfig
+	RateLimit RateLimitConfig
+
+	FaultInjection FaultInjectionConfig
+	AccessLog      AccessLogConfig
+	Compression    CompressionConfig
+	// CacheControl maps route patterns to the Cache-Control header of their successful responses
+	CacheControl map[string]string
+
+	// TrustProxyHeaders makes client IPs come from X-Forwarded-For/X-Real-IP. Only enable it
+	// behind a proxy that sets these headers.
+	TrustProxyHeaders bool
+	// TrustedProxyHops is the number of proxies in front of the service. The client IP is the
+	// X-Forwarded-For entry added by the outermost of them, entries left of it are ignored.
+	TrustedProxyHops int
+
+	// ShutdownTimeout is how long in-flight requests get to complete after a shutdown signal
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is how long the server keeps serving while reporting not ready before draining
+	ShutdownDelay time.Duration
+
+	rateLimiter   *rateLimiter
+	faultInjector *faults.Injector
 }
 
-// corsMiddleware adds CORS headers to allow cross-origin requests
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		// Handle preflight OPTIONS request
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
+// handle registers handler for pattern on mux, reachable by callers that satisfy level and limited
+// by the budget of class. Fault injection rules apply once the request is let through and
+// successful responses get the route's Cache-Control policy. The server span of the request is
+// renamed after the matched route pattern so traces are grouped per route instead of per raw URL,
+// and the pattern is made available to the outer middlewares.
+func (a *App) handle(mux *http.ServeMux, pattern string, level auth.Level, class routeClass, handler http.Handler) {
+	handler = a.authorize(level, a.rateLimit(class, a.cacheControl(pattern, a.injectFaults(pattern, class, handler))))
+
+	mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := requestInfoFromContext(r.Context()); info != nil {
+			info.route = r.Pattern
 		}
 
-		// Call the next handler
-		next.ServeHTTP(w, r)
-	})
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Pattern)
+		span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+
+		handler.ServeHTTP(w, r)
+	}))
 }
 
-func (a *App) SetupAndRun() {
+// SetupAndRun registers the routes and serves HTTP until ctx is cancelled, then shuts the server
+// down gracefully. It returns nil after a clean shutdown.
+func (a *App) SetupAndRun(ctx context.Context) error {
+	if err := a.AccessLog.validate(); err != nil {
+		a.Logger.Error("Invalid access log configuration", "error", err.Error())
+		return fmt.Errorf("invalid access log configuration: %w", err)
+	}
+	if err := a.Compression.validate(); err != nil {
+		a.Logger.Error("Invalid compression configuration", "error", err.Error())
+		return fmt.Errorf("invalid compression configuration: %w", err)
+	}
+	if err := a.CORS.validate(); err != nil {
+		a.Logger.Error("Invalid CORS configuration", "error", err.Error())
+		return fmt.Errorf("invalid CORS configuration: %w", err)
+	}
+	if err := a.TLS.validate(); err != nil {
+		a.Logger.Error("Invalid TLS configuration", "error", err.Error())
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	if err := a.RateLimit.validate(); err != nil {
+		a.Logger.Error("Invalid rate limit configuration", "error", err.Error())
+		return fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+	if a.TrustProxyHeaders && a.TrustedProxyHops < 1 {
+		a.Logger.Error("Invalid proxy configuration", "trusted_proxy_hops", strconv.Itoa(a.TrustedProxyHops))
+		return fmt.Errorf("invalid proxy configuration: trusted proxy hops must be at least 1, got %d", a.TrustedProxyHops)
+	}
+
 	mux := http.NewServeMux()
+	a.rateLimiter = newRateLimiter(a.RateLimit)
+	a.faultInjector = faults.NewInjector()
+
+	// Handlers log as their own component so their level can be changed separately
+	routesLogger := a.Logger.Named("routes")
+
+	a.handle(mux, "GET /api/quote/{id}", auth.Public, routeClassDefault, routes.HandleGetQuoteByID(routesLogger, a.Repository, a.AuthorClient))
+	a.handle(mux, "GET /api/quote/random", auth.Public, routeClassDefault, routes.HandleGetRandomQuote(routesLogger, a.Repository, a.AuthorClient))
+	a.handle(mux, "GET /api/version", auth.Public, routeClassDefault, routes.HandleGetVersion(a.Version, a.AuthorClient, routesLogger))
+	a.handle(mux, "GET /api/mock-memory", auth.Admin, routeClassExpensive, routes.HandleAutoScalingDemo(routesLogger, a.MemoryBudget))
+	a.handle(mux, "GET /api/mock-memory/stats", auth.Admin, routeClassDefault, routes.HandleMockMemoryStats(a.MemoryBudget))
+	a.handle(mux, "POST /api/mock-memory/jobs", auth.Admin, routeClassExpensive, routes.HandleCreateMockMemoryJob(routesLogger, a.MemoryBudget, a.MaxMemoryJobs))
+	a.handle(mux, "GET /api/mock-memory/jobs", auth.Admin, routeClassDefault, routes.HandleListMockMemoryJobs())
+	a.handle(mux, "GET /api/mock-memory/jobs/{id}", auth.Admin, routeClassDefault, routes.HandleGetMockMemoryJob())
+	a.handle(mux, "DELETE /api/mock-memory/jobs/{id}", auth.Admin, routeClassDefault, routes.HandleDeleteMockMemoryJob(routesLogger))
+	a.handle(mux, "POST /api/admin/scenarios", auth.Admin, routeClassExpensive, routes.HandleCreateScenario(routesLogger, a.MemoryBudget))
+	a.handle(mux, "GET /api/admin/scenarios", auth.Admin, routeClassDefault, routes.HandleListScenarios())
+	a.handle(mux, "GET /api/admin/scenarios/{id}", auth.Admin, routeClassDefault, routes.HandleGetScenario())
+	a.handle(mux, "DELETE /api/admin/scenarios/{id}", auth.Admin, routeClassDefault, routes.HandleDeleteScenario(routesLogger))
+	a.handle(mux, "GET /api/mock-cpu", auth.Admin, routeClassExpensive, routes.HandleMockCPU(routesLogger))
+	if a.FaultInjection.Enabled {
+		a.handle(mux, "GET /api/admin/faults", auth.Admin, routeClassDefault, routes.HandleGetFaults(a.faultInjector))
+		a.handle(mux, "PUT /api/admin/faults", auth.Admin, routeClassDefault, routes.HandleSetFaults(routesLogger, a.faultInjector))
+		a.handle(mux, "DELETE /api/admin/faults", auth.Admin, routeClassDefault, routes.HandleClearFaults(routesLogger, a.faultInjector))
+	}
+	if a.LogLevels != nil {
+		a.handle(mux, "GET /api/admin/log-level", auth.Admin, routeClassDefault, routes.HandleGetLogLevels(a.LogLevels))
+		a.handle(mux, "PUT /api/admin/log-level", auth.Admin, routeClassDefault, routes.HandleSetLogLevels(routesLogger, a.LogLevels))
+	}
+	a.handle(mux, "GET /metrics", auth.Public, routeClassUnlimited, a.Metrics.Handler())
+	a.handle(mux, "GET /healthz", auth.Public, routeClassUnlimited, routes.HandleLiveness())
+	a.handle(mux, "GET /readyz", auth.Public, routeClassUnlimited, routes.HandleReadiness(a.Health))
 
-	mux.HandleFunc("GET /api/quote/{id}", routes.HandleGetQuoteByID(a.Logger, a.Repository, a.AuthorClient))
-	mux.HandleFunc("GET /api/quote/random", routes.HandleGetRandomQuote(a.Logger, a.Repository, a.AuthorClient))
-	mux.HandleFunc("GET /api/version", routes.HandleGetVersion(a.Version, a.AuthorClient, a.Logger))
-	mux.HandleFunc("GET /api/mock-memory", routes.HandleAutoScalingDemo(a.Logger))
+	switch {
+	case a.Auth.Enabled() && a.AuthDisabled:
+		a.Logger.Error("Authentication can't be disabled while API keys or JWT verification keys are configured")
+		return errors.New("invalid auth configuration: disabled with credentials configured")
+	case a.AuthDisabled:
+		a.Logger.Warn("Authentication is disabled, admin routes are open to everyone")
+	case !a.Auth.Enabled():
+		a.Logger.Warn("No API keys or JWT verification keys configured, admin routes are denied")
+	}
+	if a.FaultInjection.Enabled {
+		a.Logger.Warn("Fault injection is enabled", "allow_headers", strconv.FormatBool(a.FaultInjection.AllowHeaders))
+	}
+
+	a.Metrics.RegisterGaugeFunc("mock_memory_active_allocations", "Number of mock-memory allocations currently held.",
+		func() float64 { return float64(routes.ActiveAllocations()) })
+	a.Metrics.RegisterGaugeFunc("mock_memory_active_allocation_bytes", "Total size of mock-memory allocations currently held.",
+		func() float64 { return float64(routes.ActiveAllocationBytes()) })
+	a.Metrics.RegisterGaugeFunc("mock_cpu_active_loads", "Number of mock-cpu loads currently running.",
+		func() float64 { return float64(routes.ActiveCPULoads()) })
 
-	// Wrap the mux with CORS middleware
-	handler := corsMiddleware(mux)
+	// Wrap the mux with compression and CORS middlewares, attach a trace ID to every request and
+	// log it. The OpenTelemetry handler is outermost so the trace ID middleware can reuse the ID of
+	// the server span.
+	handler := otelhttp.NewHandler(a.metricsMiddleware(traceIDMiddleware(a.accessLogMiddleware(newCORS(a.CORS, mux).middleware(a.compressMiddleware(mux))))), "quote-service",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+		// Probes and scrapes would drown the actual traffic in traces
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/metrics"
+		}),
+	)
 
 	server := &http.Server{
-		Addr:    a.Host + ":" + strconv.Itoa(a.Port),
-		Handler: handler,
+		Addr:              a.Host + ":" + strconv.Itoa(a.Port),
+		Handler:           handler,
+		ReadTimeout:       a.ReadTimeout,
+		ReadHeaderTimeout: a.ReadHeaderTimeout,
+		WriteTimeout:      a.WriteTimeout,
+		IdleTimeout:       a.IdleTimeout,
+		MaxHeaderBytes:    a.MaxHeaderBytes,
+	}
+
+	if a.TLS.Enabled() {
+		tlsConfig, err := a.TLS.build(a.Logger)
+		if err != nil {
+			a.Logger.Error("Invalid TLS configuration", "error", err.Error())
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		server.TLSConfig = tlsConfig
 	}
 
-	a.Logger.Info("Starting server", "host", a.Host, "port", strconv.Itoa(a.Port))
-	if err := server.ListenAndServe(); err != nil {
-		a.Logger.Error("Server failed to start")
-		panic(err)
+	a.Logger.Info("Starting server", "host", a.Host, "port", strconv.Itoa(a.Port),
+		"tls", strconv.FormatBool(a.TLS.Enabled()))
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		a.Logger.Error("Server failed to start", "error", err.Error())
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if a.TLS.Enabled() {
+			// The certificate comes from TLSConfig.GetCertificate
+			serveErr <- server.ServeTLS(listener, "", "")
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+	a.Health.MarkStarted()
+
+	select {
+	case err := <-serveErr:
+		a.Logger.Error("Server failed", "error", err.Error())
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
 	}
+
+	return a.shutdown(server)
+}
+
+// shutdown drains the server: readiness flips to not ready, in-flight and queued mock-memory
+// requests and scenarios are released, mock-cpu loads are stopped and in-flight requests get up to
+// ShutdownTimeout to complete.
+func (a *App) shutdown(server *http.Server) error {
+	a.Logger.Info("Shutdown signal received, draining", "shutdown_timeout", a.ShutdownTimeout.String())
+	a.Health.StartDraining()
+
+	// Give load balancers time to notice the failing readiness probe before refusing connections
+	if a.ShutdownDelay > 0 {
+		time.Sleep(a.ShutdownDelay)
+	}
+
+	// Queued mock-memory requests must not allocate once holds are released
+	a.MemoryBudget.Close()
+	routes.ReleaseActiveAllocations()
+	routes.StopCPULoads()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		a.Logger.Error("Graceful shutdown timed out, closing remaining connections", "error", err.Error())
+		server.Close()
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	a.Logger.Info("Server stopped")
+	return nil
 }
@@ -0,0 +1,62 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+	"quote-service/internal/auth"
+)
+
+// authorize enforces the authentication level of a route. The principal of authenticated
+// requests is added to the request context. On public routes credentials are optional and
+// invalid ones are ignored. Without configured credentials only public routes are served, unless
+// authentication is explicitly disabled.
+func (a *App) authorize(level auth.Level, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Auth.Enabled() {
+			if level != auth.Public && !a.AuthDisabled {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Failed authentications have their own per-IP budget, checked before the credentials
+		// are verified and independent of the route's rate limit, which is keyed by principal
+		if auth.HasCredentials(r) && !a.allowAuthentication(w, r) {
+			return
+		}
+
+		principal, err := a.Auth.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				a.recordAuthenticationFailure(r)
+			}
+			if level == auth.Public {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !errors.Is(err, auth.ErrNoCredentials) {
+				a.Logger.WarnWithCtx(r.Context(), "Authentication failed", "route", r.Pattern, "error", err.Error())
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="quote-service"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if level == auth.Admin && !principal.IsAdmin() {
+			a.Logger.WarnWithCtx(r.Context(), "Admin access denied",
+				"route", r.Pattern, "principal", principal.Subject, "auth_method", principal.Method)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if level != auth.Public {
+			a.Logger.InfoWithCtx(r.Context(), "Authorized request",
+				"route", r.Pattern, "principal", principal.Subject, "auth_method", principal.Method)
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
@@ -0,0 +1,94 @@
+package restapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"quote-service/internal/auth"
+	"quote-service/internal/ratelimit"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+)
+
+func TestAuthorizeWithoutCredentialsConfigured(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(auth.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name     string
+		level    auth.Level
+		disabled bool
+		want     int
+	}{
+		{"public", auth.Public, false, http.StatusOK},
+		{"admin denied", auth.Admin, false, http.StatusForbidden},
+		{"admin with auth disabled", auth.Admin, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{Auth: authenticator, AuthDisabled: tt.disabled}
+			w := httptest.NewRecorder()
+			a.authorize(tt.level, ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeLimitsFailedAuthentications(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret"))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeys: []string{"ops:admin:" + hex.EncodeToString(hash[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{
+		Logger: testlogger.New(),
+		Auth:   authenticator,
+		RateLimit: RateLimitConfig{
+			Enabled:      true,
+			Default:      ratelimit.Limit{Rate: 100, Burst: 100},
+			AuthFailures: ratelimit.Limit{Rate: 0.001, Burst: 2},
+		},
+	}
+	a.rateLimiter = newRateLimiter(a.RateLimit)
+	handler := a.authorize(auth.Admin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(key, remoteAddr string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/faults", nil)
+		r.RemoteAddr = remoteAddr
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	steps := []struct {
+		key        string
+		remoteAddr string
+		want       int
+	}{
+		{"guess-1", "192.0.2.1:1234", http.StatusUnauthorized},
+		{"guess-2", "192.0.2.1:1234", http.StatusUnauthorized},
+		{"guess-3", "192.0.2.1:1234", http.StatusTooManyRequests},
+		// The budget is used up, even the right key isn't verified anymore
+		{"secret", "192.0.2.1:1234", http.StatusTooManyRequests},
+		// Requests without credentials don't use the budget
+		{"", "192.0.2.1:1234", http.StatusUnauthorized},
+		// Other clients have their own budget
+		{"secret", "192.0.2.2:1234", http.StatusOK},
+	}
+	for i, step := range steps {
+		if got := request(step.key, step.remoteAddr); got != step.want {
+			t.Errorf("request %d with key %q from %s: status = %d, want %d", i+1, step.key, step.remoteAddr, got, step.want)
+		}
+	}
+}
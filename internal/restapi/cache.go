@@ -0,0 +1,49 @@
+package restapi
+
+import "net/http"
+
+// cacheControlWriter sets the Cache-Control policy of the route on successful responses. Other
+// responses get "no-store" so caches don't keep errors for as long as the content.
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (cw *cacheControlWriter) WriteHeader(statusCode int) {
+	if !cw.wroteHeader && statusCode >= http.StatusOK {
+		cw.wroteHeader = true
+		if cw.Header().Get("Cache-Control") == "" {
+			if statusCode == http.StatusOK || statusCode == http.StatusNotModified {
+				cw.Header().Set("Cache-Control", cw.policy)
+			} else {
+				cw.Header().Set("Cache-Control", "no-store")
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *cacheControlWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// cacheControl applies the Cache-Control policy configured for pattern, if any
+func (a *App) cacheControl(pattern string, next http.Handler) http.Handler {
+	policy, ok := a.CacheControl[pattern]
+	if !ok {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, policy: policy}, r)
+	})
+}
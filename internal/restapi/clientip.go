@@ -0,0 +1,54 @@
+package restapi
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the IP address of the client. X-Forwarded-For and X-Real-IP are only honoured
+// when trustedProxyHops is positive, as clients can send them freely when not behind a proxy.
+// Each proxy appends the address it received the request from to X-Forwarded-For, so only the
+// trustedProxyHops right-most entries were added by trusted proxies and the left-most of those is
+// the client. Entries further left come from the client and may be spoofed.
+func clientIP(r *http.Request, trustedProxyHops int) string {
+	if trustedProxyHops > 0 {
+		if ip, ok := forwardedFor(r.Header.Values("X-Forwarded-For"), trustedProxyHops); ok {
+			return ip
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the X-Forwarded-For entry added by the outermost of hops trusted proxies.
+// When there are fewer entries the request passed fewer proxies and the left-most one is used.
+func forwardedFor(headers []string, hops int) (string, bool) {
+	var entries []string
+	for _, header := range headers {
+		for entry := range strings.SplitSeq(header, ",") {
+			entries = append(entries, strings.TrimSpace(entry))
+		}
+	}
+	if len(entries) == 0 {
+		return "", false
+	}
+
+	ip := entries[max(0, len(entries)-hops)]
+	return ip, ip != ""
+}
+
+// clientIP returns the IP address of the client of r, honouring proxy headers as configured
+func (a *App) clientIP(r *http.Request) string {
+	if !a.TrustProxyHeaders {
+		return clientIP(r, 0)
+	}
+	return clientIP(r, a.TrustedProxyHops)
+}
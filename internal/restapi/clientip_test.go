@@ -0,0 +1,63 @@
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		forwardedFor []string
+		realIP       string
+		trustHeaders bool
+		hops         int
+		want         string
+	}{
+		{name: "proxy headers not trusted", forwardedFor: []string{"198.51.100.7"}, want: "192.0.2.1"},
+		{name: "single proxy", forwardedFor: []string{"198.51.100.7"}, trustHeaders: true, hops: 1, want: "198.51.100.7"},
+		{
+			name:         "spoofed entries are ignored",
+			forwardedFor: []string{"203.0.113.99, 10.0.0.1, 198.51.100.7"},
+			trustHeaders: true,
+			hops:         1,
+			want:         "198.51.100.7",
+		},
+		{
+			name:         "spoofed entries behind two proxies",
+			forwardedFor: []string{"203.0.113.99, 198.51.100.7, 10.0.0.2"},
+			trustHeaders: true,
+			hops:         2,
+			want:         "198.51.100.7",
+		},
+		{
+			name:         "spoofed header line",
+			forwardedFor: []string{"203.0.113.99", "198.51.100.7"},
+			trustHeaders: true,
+			hops:         1,
+			want:         "198.51.100.7",
+		},
+		{name: "fewer entries than hops", forwardedFor: []string{"198.51.100.7"}, trustHeaders: true, hops: 3, want: "198.51.100.7"},
+		{name: "X-Real-IP", realIP: "198.51.100.8", trustHeaders: true, hops: 1, want: "198.51.100.8"},
+		{name: "no proxy headers", trustHeaders: true, hops: 1, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			a := &App{TrustProxyHeaders: tt.trustHeaders, TrustedProxyHops: tt.hops}
+			if got := a.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
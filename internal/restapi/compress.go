@@ -0,0 +1,318 @@
+package restapi
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	restapiutils "quote-service/internal/restapi/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings supported by the compression middleware
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+)
+
+// CompressionConfig configures response compression negotiated from Accept-Encoding
+type CompressionConfig struct {
+	Enabled bool
+	// MinSize is the body size in bytes below which responses are sent uncompressed
+	MinSize int
+	// Encodings are the offered encodings in order of preference, used when the client accepts
+	// several with the same quality
+	Encodings []string
+}
+
+func (c CompressionConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MinSize < 0 {
+		return errors.New("minimum size must not be negative")
+	}
+	if len(c.Encodings) == 0 {
+		return errors.New("at least one encoding is required")
+	}
+	for _, encoding := range c.Encodings {
+		if _, ok := encoders[encoding]; !ok {
+			return fmt.Errorf("unknown encoding %q, must be one of %s, %s, %s", encoding, EncodingBrotli, EncodingZstd, EncodingGzip)
+		}
+	}
+	return nil
+}
+
+// encoder is a pooled compressor. Writers are reset to a new destination before use and put back
+// once closed.
+type encoder struct {
+	pool sync.Pool
+}
+
+// compressWriter is the common interface of the gzip, brotli and zstd writers
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// zstdWriter adapts the Reset of zstd.Encoder, which returns an error
+type zstdWriter struct {
+	*zstd.Encoder
+}
+
+func (z zstdWriter) Reset(w io.Writer) {
+	z.Encoder.Reset(w)
+}
+
+var encoders = map[string]*encoder{
+	EncodingBrotli: {pool: sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}}},
+	EncodingZstd: {pool: sync.Pool{New: func() any {
+		// Concurrency 1 keeps the encoder synchronous, requests are already concurrent
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return zstdWriter{enc}
+	}}},
+	EncodingGzip: {pool: sync.Pool{New: func() any {
+		return gzip.NewWriter(nil)
+	}}},
+}
+
+func (e *encoder) get(w io.Writer) compressWriter {
+	cw := e.pool.Get().(compressWriter)
+	cw.Reset(w)
+	return cw
+}
+
+func (e *encoder) put(cw compressWriter) {
+	e.pool.Put(cw)
+}
+
+// negotiateEncoding returns the encoding of offered with the highest quality in the
+// Accept-Encoding header values, "" if none is acceptable
+func negotiateEncoding(acceptEncoding []string, offered []string) string {
+	qualities := make(map[string]float64)
+	for _, header := range acceptEncoding {
+		for entry := range strings.SplitSeq(header, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+			q := 1.0
+			if qStr, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				var err error
+				if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+					continue
+				}
+			}
+			qualities[strings.ToLower(strings.TrimSpace(coding))] = q
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range offered {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQuality {
+			best, bestQuality = encoding, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of contentType benefit from compression
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		slices.Contains([]string{"application/json", "application/javascript", "application/xml"}, mediaType) ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// compressResponseWriter buffers the body until MinSize bytes are written, then compresses the
+// rest of it. Smaller bodies, bodies that are already encoded and bodies of content types that
+// don't compress well are written unchanged.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	encoder  *encoder
+	minSize  int
+
+	statusCode  int
+	buf         []byte
+	writer      compressWriter
+	passthrough bool
+	// revalidatesEncoded is set when the client's If-None-Match had tags of the encoded
+	// representation, so a 304 carries that tag too
+	revalidatesEncoded bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(statusCode int) {
+	if cw.statusCode != 0 || cw.passthrough {
+		return
+	}
+	// Informational responses don't end the response, bodiless ones can't be compressed
+	if statusCode < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	cw.statusCode = statusCode
+	if statusCode == http.StatusNotModified && cw.revalidatesEncoded {
+		cw.setEncodedETag()
+	}
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		cw.startPassthrough()
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if cw.writer != nil {
+		return cw.writer.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start decides whether to compress once the body is large enough, and writes the buffered body
+func (cw *compressResponseWriter) start() error {
+	header := cw.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if header.Get("Content-Encoding") != "" || !compressible(header.Get("Content-Type")) {
+		return cw.flushPassthrough()
+	}
+
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.setEncodedETag()
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	cw.writer = cw.encoder.get(cw.ResponseWriter)
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.writer.Write(buf)
+	return err
+}
+
+// setEncodedETag gives the encoded representation its own strong tag, e.g. "abc-gzip", since its
+// bytes differ from the identity representation
+func (cw *compressResponseWriter) setEncodedETag() {
+	if etag := cw.Header().Get("ETag"); etag != "" {
+		cw.Header().Set("ETag", restapiutils.ETagVariant(etag, cw.encoding))
+	}
+}
+
+// startPassthrough sends the status, after which the body is written unchanged
+func (cw *compressResponseWriter) startPassthrough() {
+	cw.passthrough = true
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+}
+
+func (cw *compressResponseWriter) flushPassthrough() error {
+	cw.startPassthrough()
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends what has been written so far, compressed if compression has started. A flush before
+// MinSize bytes have been written sends the body uncompressed.
+func (cw *compressResponseWriter) Flush() {
+	switch {
+	case cw.writer != nil:
+		cw.writer.Flush()
+	case !cw.passthrough:
+		if cw.statusCode == 0 {
+			cw.statusCode = http.StatusOK
+		}
+		if len(cw.buf) == 0 {
+			return
+		}
+		cw.flushPassthrough()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// close ends the compressed stream or writes the body that stayed below MinSize
+func (cw *compressResponseWriter) close() error {
+	if cw.writer != nil {
+		err := cw.writer.Close()
+		cw.writer.Reset(io.Discard)
+		cw.encoder.put(cw.writer)
+		cw.writer = nil
+		return err
+	}
+	if cw.passthrough || cw.statusCode == 0 {
+		return nil
+	}
+	return cw.flushPassthrough()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressMiddleware compresses response bodies with the best encoding accepted by the client
+func (a *App) compressMiddleware(next http.Handler) http.Handler {
+	if !a.Compression.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"), a.Compression.Encodings)
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			encoder:        encoders[encoding],
+			minSize:        a.Compression.MinSize,
+		}
+		defer cw.close()
+
+		// Handlers compare If-None-Match with the tag of the identity representation
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+			if stripped, ok := restapiutils.StripETagVariant(ifNoneMatch, encoding); ok {
+				r = r.Clone(r.Context())
+				r.Header.Set("If-None-Match", stripped)
+				cw.revalidatesEncoded = true
+			}
+		}
+
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,173 @@
+package restapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	restapiutils "quote-service/internal/restapi/utils"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingBrotli},
+		{"gzip;q=0.8, zstd;q=0.9, br;q=0.1", EncodingZstd},
+		{"*", EncodingBrotli},
+		{"*, br;q=0", EncodingZstd},
+		{"GZIP", EncodingGzip},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding([]string{tt.acceptEncoding}, offered); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"message":"lorem ipsum"}`, 100)
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"": func(r io.Reader) (io.Reader, error) { return r, nil },
+		EncodingGzip: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		EncodingBrotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		EncodingZstd: func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		},
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", body: large, wantEncoding: EncodingGzip},
+		{name: "brotli", acceptEncoding: "br, gzip", body: large, wantEncoding: EncodingBrotli},
+		{name: "zstd", acceptEncoding: "zstd", body: large, wantEncoding: EncodingZstd},
+		{name: "below minimum size", acceptEncoding: "gzip", body: `{"id":1}`},
+		{name: "not accepted", acceptEncoding: "identity", body: large},
+		{name: "incompressible type", acceptEncoding: "gzip", contentType: "image/png", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{Compression: CompressionConfig{
+				Enabled:   true,
+				MinSize:   1024,
+				Encodings: []string{EncodingBrotli, EncodingZstd, EncodingGzip},
+			}}
+			handler := app.compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(http.StatusCreated)
+				// Small writes so the body crosses the minimum size while it's being written
+				for i := 0; i < len(tt.body); i += 100 {
+					io.WriteString(w, tt.body[i:min(i+100, len(tt.body))])
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			reader, err := decoders[tt.wantEncoding](w.Body)
+			if err != nil {
+				t.Fatalf("failed to create decoder: %v", err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestCompressMiddlewareETag(t *testing.T) {
+	app := &App{Compression: CompressionConfig{
+		Enabled:   true,
+		MinSize:   1024,
+		Encodings: []string{EncodingBrotli, EncodingZstd, EncodingGzip},
+	}}
+	newHandler := func(message string) http.Handler {
+		return app.compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"abc"`)
+			restapiutils.WriteResponse(w, r, http.StatusOK, map[string]string{"message": message}, restapiutils.Representations{})
+		}))
+	}
+	large, small := newHandler(strings.Repeat("lorem ipsum ", 200)), newHandler("lorem ipsum")
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		acceptEncoding string
+		ifNoneMatch    string
+		wantStatus     int
+		wantETag       string
+	}{
+		{"gzip", large, "gzip", "", http.StatusOK, `"abc-gzip"`},
+		{"gzip revalidated", large, "gzip", `"abc-gzip"`, http.StatusNotModified, `"abc-gzip"`},
+		{"weak gzip revalidated", large, "gzip", `W/"abc-gzip"`, http.StatusNotModified, `"abc-gzip"`},
+		{"other encoding revalidated", large, "br", `"abc-gzip"`, http.StatusOK, `"abc-br"`},
+		{"identity", large, "", "", http.StatusOK, `"abc"`},
+		{"identity revalidated", large, "", `"abc"`, http.StatusNotModified, `"abc"`},
+		{"below minimum size", small, "gzip", "", http.StatusOK, `"abc"`},
+		{"below minimum size revalidated", small, "gzip", `"abc"`, http.StatusNotModified, `"abc"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", got, tt.wantETag)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with a body of %d bytes", w.Body.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the cross-origin policy applied to all routes. Allowed methods aren't configured:
+// they are the methods registered for the requested path.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin requests. "*" allows any
+	// origin and a "*." prefix on the host allows all its subdomains, e.g. "https://*.example.com".
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func (c CORSConfig) validate() error {
+	// Reflecting any origin with credentials would let every site make authenticated requests
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return errors.New(`allowed origins must be listed explicitly when credentials are allowed, "*" is not allowed`)
+	}
+	return nil
+}
+
+// corsProbeMethods are the methods checked against the mux to find what a path supports
+var corsProbeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// cors applies a CORSConfig. Preflight requests are answered directly and rejected with 403 if
+// the origin, method or headers aren't allowed.
+type cors struct {
+	config CORSConfig
+	mux    *http.ServeMux
+
+	allowedHeaders map[string]bool
+}
+
+func newCORS(config CORSConfig, mux *http.ServeMux) *cors {
+	allowedHeaders := make(map[string]bool, len(config.AllowedHeaders))
+	for _, header := range config.AllowedHeaders {
+		allowedHeaders[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+
+	return &cors{
+		config:         config,
+		mux:            mux,
+		allowedHeaders: allowedHeaders,
+	}
+}
+
+func (c *cors) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the Origin header, caches must not share it between origins
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.handlePreflight(w, r, origin)
+			return
+		}
+
+		if origin != "" && c.originAllowed(origin) {
+			c.setOriginHeaders(w, origin)
+			if len(c.config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.config.ExposedHeaders, ", "))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *cors) handlePreflight(w http.ResponseWriter, r *http.Request, origin string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if !c.originAllowed(origin) {
+		http.Error(w, "CORS origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	methods := c.routeMethods(r)
+	if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+		http.Error(w, "CORS method not allowed", http.StatusForbidden)
+		return
+	}
+
+	requestedHeaders := r.Header.Get("Access-Control-Request-Headers")
+	for _, header := range strings.Split(requestedHeaders, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !c.allowedHeaders[header] {
+			http.Error(w, "CORS header not allowed", http.StatusForbidden)
+			return
+		}
+	}
+
+	c.setOriginHeaders(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if requestedHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.config.AllowedHeaders, ", "))
+	}
+	if c.config.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.config.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOriginHeaders allows origin, which must have passed originAllowed. Configurations with "*"
+// never allow credentials, so origins are only reflected when they are listed or match a
+// subdomain pattern.
+func (c *cors) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if slices.Contains(c.config.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// routeMethods returns the methods registered on the mux for the request path
+func (c *cors) routeMethods(r *http.Request) []string {
+	var methods []string
+	for _, method := range corsProbeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := c.mux.Handler(probe); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+func (c *cors) originAllowed(origin string) bool {
+	for _, allowed := range c.config.AllowedOrigins {
+		if allowed == "*" && !c.config.AllowCredentials || strings.EqualFold(allowed, origin) || matchWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWildcardOrigin matches origins against patterns like "https://*.example.com". The wildcard
+// covers one or more subdomain labels but not the bare domain.
+func matchWildcardOrigin(pattern, origin string) bool {
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+
+	prefix := scheme + "://"
+	if !strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) {
+		return false
+	}
+
+	originHost := origin[len(prefix):]
+	suffix := "." + host
+	return len(originHost) > len(suffix) && strings.HasSuffix(strings.ToLower(originHost), strings.ToLower(suffix))
+}
@@ -0,0 +1,126 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"quote-service/internal/auth"
+	"quote-service/internal/faults"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slowBodyInterval is how often a throttled response body writes a chunk
+const slowBodyInterval = 100 * time.Millisecond
+
+type FaultInjectionConfig struct {
+	// Enabled registers the /api/admin/faults routes and applies their rules. Keep it off in
+	// production.
+	Enabled bool
+	// AllowHeaders lets admins inject faults into their own requests with X-Fault-* headers. The
+	// headers of other callers are ignored.
+	AllowHeaders bool
+}
+
+// injectFaults applies the fault rule of pattern, or the one from the request headers, to every
+// request. Probes, metrics and admin routes are never faulted so the pod stays manageable.
+func (a *App) injectFaults(pattern string, class routeClass, next http.Handler) http.Handler {
+	if !a.FaultInjection.Enabled || class == routeClassUnlimited || strings.Contains(pattern, " /api/admin/") {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := a.faultInjector.Match(pattern)
+		if a.FaultInjection.AllowHeaders && a.mayInjectFaults(r) {
+			headerRule, found, err := faults.FromHeaders(pattern, r.Header)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if found {
+				rule, ok = headerRule, true
+			}
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if latency := rule.Latency(); latency > 0 {
+			a.Logger.DebugWithCtx(r.Context(), "Injecting latency", "route", pattern, "latency", latency.String())
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(latency):
+			}
+		}
+
+		if rule.ShouldDrop() {
+			a.Logger.WarnWithCtx(r.Context(), "Injecting dropped connection", "route", pattern)
+			// The server closes the connection without writing a response or logging the panic
+			panic(http.ErrAbortHandler)
+		}
+
+		if rule.ShouldFail() {
+			a.Logger.WarnWithCtx(r.Context(), "Injecting error response", "route", pattern, "status", strconv.Itoa(rule.Status()))
+			http.Error(w, "Injected fault", rule.Status())
+			return
+		}
+
+		if rule.SlowBodyBytesPerSecond > 0 {
+			w = newSlowBodyWriter(r.Context(), w, rule.SlowBodyBytesPerSecond)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mayInjectFaults reports whether the caller may fault its own request with headers. That's
+// reserved for admins, who can set the same faults for every caller through the admin routes.
+// With authentication disabled those routes are open, and so are the headers.
+func (a *App) mayInjectFaults(r *http.Request) bool {
+	if !a.Auth.Enabled() {
+		return a.AuthDisabled
+	}
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	return ok && principal.IsAdmin()
+}
+
+// slowBodyWriter throttles the response body by flushing it in small chunks
+type slowBodyWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	chunkSize int
+}
+
+func newSlowBodyWriter(ctx context.Context, w http.ResponseWriter, bytesPerSecond int) *slowBodyWriter {
+	// Dividing first keeps large rates from overflowing
+	chunkSize := max(1, bytesPerSecond/int(time.Second/slowBodyInterval))
+	return &slowBodyWriter{ResponseWriter: w, ctx: ctx, chunkSize: chunkSize}
+}
+
+func (sw *slowBodyWriter) Write(b []byte) (int, error) {
+	controller := http.NewResponseController(sw.ResponseWriter)
+
+	written := 0
+	for written < len(b) {
+		end := min(written+sw.chunkSize, len(b))
+		n, err := sw.ResponseWriter.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		controller.Flush()
+
+		select {
+		case <-sw.ctx.Done():
+			return written, sw.ctx.Err()
+		case <-time.After(slowBodyInterval):
+		}
+	}
+	return written, nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sw *slowBodyWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
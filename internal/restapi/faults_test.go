@@ -0,0 +1,84 @@
+package restapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"quote-service/internal/auth"
+	"quote-service/internal/faults"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+)
+
+func TestInjectFaultsHeadersOnlyForAdmins(t *testing.T) {
+	apiKey := func(name, roles, key string) string {
+		hash := sha256.Sum256([]byte(key))
+		return name + ":" + roles + ":" + hex.EncodeToString(hash[:])
+	}
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeys: []string{
+		apiKey("ops", "admin", "admin-key"),
+		apiKey("app", "", "reader-key"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &App{
+		Logger:         testlogger.New(),
+		Auth:           authenticator,
+		FaultInjection: FaultInjectionConfig{Enabled: true, AllowHeaders: true},
+		faultInjector:  faults.NewInjector(),
+	}
+	pattern := "GET /api/quote/random"
+	handler := a.authorize(auth.Public, a.injectFaults(pattern, routeClassDefault,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"anonymous", "", http.StatusOK},
+		{"non-admin", "reader-key", http.StatusOK},
+		{"admin", "admin-key", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/quote/random", nil)
+			r.Header.Set(faults.HeaderErrorPercent, "100")
+			r.Header.Set(faults.HeaderErrorStatus, "503")
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlowBodyWriterChunkSize(t *testing.T) {
+	tests := []struct {
+		bytesPerSecond int
+		want           int
+	}{
+		{bytesPerSecond: 1, want: 1},
+		{bytesPerSecond: 100, want: 10},
+		{bytesPerSecond: 100 * 1024 * 1024, want: 10 * 1024 * 1024},
+		{bytesPerSecond: math.MaxInt, want: math.MaxInt / 10},
+	}
+
+	for _, tt := range tests {
+		sw := newSlowBodyWriter(context.Background(), httptest.NewRecorder(), tt.bytesPerSecond)
+		if sw.chunkSize != tt.want {
+			t.Errorf("chunk size at %d B/s = %d, want %d", tt.bytesPerSecond, sw.chunkSize, tt.want)
+		}
+	}
+}
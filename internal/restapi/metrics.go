@@ -0,0 +1,70 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// unmatchedRoute is the route label of requests that did not match any registered pattern
+const unmatchedRoute = "unmatched"
+
+type requestInfoKey struct{}
+
+// requestInfo is shared between the outer middlewares and the matched route handler, which
+// fills in the route pattern that is only known after the mux has routed the request.
+type requestInfo struct {
+	route string
+}
+
+func withRequestInfo(ctx context.Context, info *requestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// responseRecorder captures the status code and body size written by the wrapped handler
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	bytes       int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.wroteHeader = true
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (flushing, hijacking, deadlines)
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// metricsMiddleware records request count and latency per route, method and status code
+func (a *App) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{route: unmatchedRoute}
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r.WithContext(withRequestInfo(r.Context(), info)))
+
+		a.Metrics.ObserveHTTPRequest(info.route, r.Method, rec.statusCode, time.Since(start))
+	})
+}
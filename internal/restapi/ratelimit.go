@@ -0,0 +1,160 @@
+package restapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"quote-service/internal/auth"
+	"quote-service/internal/ratelimit"
+	"strconv"
+	"time"
+)
+
+// routeClass groups routes that share a rate limit budget
+type routeClass string
+
+const (
+	// routeClassDefault is for regular API routes
+	routeClassDefault routeClass = "default"
+	// routeClassExpensive is for routes that hold significant resources, like mock-memory
+	routeClassExpensive routeClass = "expensive"
+	// routeClassUnlimited is for probes and metrics scrapes, which are never rate limited
+	routeClassUnlimited routeClass = "unlimited"
+)
+
+type RateLimitConfig struct {
+	Enabled bool
+
+	// Default and Expensive are the per-client budgets of the route classes
+	Default   ratelimit.Limit
+	Expensive ratelimit.Limit
+
+	// ExpensiveMaxConcurrent caps in-flight requests to expensive routes across all clients.
+	// Zero means no cap.
+	ExpensiveMaxConcurrent int
+
+	// AuthFailures is the per-IP budget of failed authentications. Once it's used up, requests
+	// with credentials get 429 before they are verified, so keys can't be guessed at the route
+	// rate.
+	AuthFailures ratelimit.Limit
+}
+
+func (c RateLimitConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	limits := []struct {
+		name  string
+		limit ratelimit.Limit
+	}{
+		{"default", c.Default},
+		{"expensive", c.Expensive},
+		{"auth failure", c.AuthFailures},
+	}
+	for _, l := range limits {
+		if err := l.limit.Validate(); err != nil {
+			return fmt.Errorf("%s limit: %w", l.name, err)
+		}
+	}
+	if c.ExpensiveMaxConcurrent < 0 {
+		return fmt.Errorf("expensive max concurrent must not be negative, got %d", c.ExpensiveMaxConcurrent)
+	}
+	return nil
+}
+
+// rateLimiter holds the per-client buckets of every route class and the concurrency slots of
+// expensive routes
+type rateLimiter struct {
+	limiters      map[routeClass]*ratelimit.Limiter
+	expensiveSlot chan struct{}
+	authFailures  *ratelimit.Limiter
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		limiters: map[routeClass]*ratelimit.Limiter{
+			routeClassDefault:   ratelimit.NewLimiter(config.Default),
+			routeClassExpensive: ratelimit.NewLimiter(config.Expensive),
+		},
+		authFailures: ratelimit.NewLimiter(config.AuthFailures),
+	}
+	if config.ExpensiveMaxConcurrent > 0 {
+		rl.expensiveSlot = make(chan struct{}, config.ExpensiveMaxConcurrent)
+	}
+
+	return rl
+}
+
+// rateLimit enforces the budget of class. Clients are identified by their authenticated principal,
+// falling back to the client IP, so it must run after authorize.
+func (a *App) rateLimit(class routeClass, next http.Handler) http.Handler {
+	if !a.RateLimit.Enabled || class == routeClassUnlimited {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := "ip:" + a.clientIP(r)
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			key = principal.Method + ":" + principal.Subject
+		}
+
+		result := a.rateLimiter.limiters[class].Allow(key)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			a.Logger.WarnWithCtx(r.Context(), "Rate limit exceeded", "route", r.Pattern, "class", string(class), "client", key)
+			w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		if class == routeClassExpensive && a.rateLimiter.expensiveSlot != nil {
+			select {
+			case a.rateLimiter.expensiveSlot <- struct{}{}:
+				defer func() { <-a.rateLimiter.expensiveSlot }()
+			default:
+				a.Logger.WarnWithCtx(r.Context(), "Concurrency limit reached", "route", r.Pattern, "client", key)
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowAuthentication reports whether the client of r still has failed authentications left,
+// writing a 429 response otherwise. It runs before credentials are verified.
+func (a *App) allowAuthentication(w http.ResponseWriter, r *http.Request) bool {
+	if !a.RateLimit.Enabled {
+		return true
+	}
+
+	ip := a.clientIP(r)
+	result := a.rateLimiter.authFailures.Peek("ip:" + ip)
+	if result.Allowed {
+		return true
+	}
+
+	a.Logger.WarnWithCtx(r.Context(), "Authentication failure limit exceeded", "route", r.Pattern, "client", "ip:"+ip)
+	w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+	http.Error(w, "Too many failed authentications", http.StatusTooManyRequests)
+	return false
+}
+
+// recordAuthenticationFailure takes a token from the failed authentication budget of the client
+// of r
+func (a *App) recordAuthenticationFailure(r *http.Request) {
+	if a.RateLimit.Enabled {
+		a.rateLimiter.authFailures.Allow("ip:" + a.clientIP(r))
+	}
+}
+
+// ceilSeconds formats d as whole seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
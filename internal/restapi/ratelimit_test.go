@@ -0,0 +1,78 @@
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quote-service/internal/ratelimit"
+	"quote-service/pkg/logger/testlogger"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	a := &App{
+		Logger:            testlogger.New(),
+		TrustProxyHeaders: true,
+		TrustedProxyHops:  1,
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: ratelimit.Limit{Rate: 0.001, Burst: 2},
+		},
+	}
+	a.rateLimiter = newRateLimiter(a.RateLimit)
+	handler := a.rateLimit(routeClassDefault, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// The client makes up a new left-most entry for every request, the proxy appends its address
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/api/quote/random", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "203.0.113."+strconv.Itoa(i)+", 198.51.100.7")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	valid := ratelimit.Limit{Rate: 1, Burst: 1}
+
+	tests := []struct {
+		name    string
+		config  RateLimitConfig
+		wantErr bool
+	}{
+		{name: "valid", config: RateLimitConfig{Enabled: true, Default: valid, Expensive: valid, AuthFailures: valid}},
+		{name: "disabled", config: RateLimitConfig{}},
+		{
+			name:    "zero burst",
+			config:  RateLimitConfig{Enabled: true, Default: ratelimit.Limit{Rate: 1}, Expensive: valid, AuthFailures: valid},
+			wantErr: true,
+		},
+		{
+			name:    "zero rate",
+			config:  RateLimitConfig{Enabled: true, Default: valid, Expensive: ratelimit.Limit{Burst: 1}, AuthFailures: valid},
+			wantErr: true,
+		},
+		{
+			name:    "negative auth failure rate",
+			config:  RateLimitConfig{Enabled: true, Default: valid, Expensive: valid, AuthFailures: ratelimit.Limit{Rate: -1, Burst: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "negative concurrency cap",
+			config:  RateLimitConfig{Enabled: true, Default: valid, Expensive: valid, AuthFailures: valid, ExpensiveMaxConcurrent: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
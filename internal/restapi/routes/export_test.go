@@ -0,0 +1,28 @@
+package routes
+
+// ResetAllocations undoes ReleaseActiveAllocations, so tests of the shutdown don't affect other
+// tests
+func ResetAllocations() {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	allocationsStopped = false
+}
+
+// SetLastRequestID makes newRequestID continue after id
+func SetLastRequestID(id uint64) {
+	lastRequestID.Store(id)
+}
+
+// ResetCPULoads undoes StopCPULoads, so tests of the shutdown don't affect other tests
+func ResetCPULoads() {
+	cpuLoadsMutex.Lock()
+	defer cpuLoadsMutex.Unlock()
+
+	cpuLoadsStopped = false
+}
+
+// ScenarioPhaseLevels returns the memory and CPU load of a step of phase
+func ScenarioPhaseLevels(phase ScenarioPhase, previousMemoryMB int, previousLoad float64, step int) (int, float64) {
+	return phase.levels(previousMemoryMB, previousLoad, step)
+}
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"quote-service/internal/faults"
+	restapiutils "quote-service/internal/restapi/utils"
+	"quote-service/pkg/logger"
+	"strconv"
+)
+
+// maxFaultRulesBodyBytes bounds the body of fault rule updates
+const maxFaultRulesBodyBytes = 1 << 20
+
+type faultRulesResponse struct {
+	Rules []faults.Rule `json:"rules"`
+}
+
+// HandleGetFaults
+// /api/admin/faults
+// Lists the fault injection rules currently in effect.
+func HandleGetFaults(injector *faults.Injector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		restapiutils.WriteJSONResponse(w, http.StatusOK, faultRulesResponse{Rules: injector.Rules()})
+	}
+}
+
+// HandleSetFaults
+// /api/admin/faults
+// Replaces all fault injection rules with the ones in the request body, e.g.
+// {"rules": [{"route": "GET /api/quote/{id}", "latency_ms": 200, "error_percent": 10}]}.
+// The route "*" applies to every route without a rule of its own.
+func HandleSetFaults(logger logger.Logger, injector *faults.Injector) http.HandlerFunc {
+	type Request struct {
+		Rules []faults.Rule `json:"rules"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFaultRulesBodyBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			errorResp := requestError{
+				Error:   "Invalid request body",
+				Code:    400,
+				Details: err.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		if err := injector.SetRules(req.Rules); err != nil {
+			errorResp := requestError{
+				Error:   "Invalid fault rules",
+				Code:    400,
+				Details: err.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		logger.WarnWithCtx(r.Context(), "Fault injection rules updated", "rules", strconv.Itoa(len(req.Rules)))
+		restapiutils.WriteJSONResponse(w, http.StatusOK, faultRulesResponse{Rules: injector.Rules()})
+	}
+}
+
+// HandleClearFaults
+// /api/admin/faults
+// Removes all fault injection rules.
+func HandleClearFaults(logger logger.Logger, injector *faults.Injector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		injector.Clear()
+
+		logger.InfoWithCtx(r.Context(), "Fault injection rules cleared")
+		restapiutils.WriteJSONResponse(w, http.StatusOK, faultRulesResponse{Rules: injector.Rules()})
+	}
+}
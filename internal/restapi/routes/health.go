@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+	"quote-service/internal/health"
+	restapiutils "quote-service/internal/restapi/utils"
+)
+
+// HandleLiveness
+// /healthz
+// Reports that the process is up and serving HTTP. It doesn't check any dependency so a failing
+// dependency never gets the pod restarted.
+func HandleLiveness() http.HandlerFunc {
+	type Response struct {
+		Status string `json:"status"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		restapiutils.WriteJSONResponse(w, http.StatusOK, Response{Status: health.StatusOK})
+	}
+}
+
+// HandleReadiness
+// /readyz
+// Reports whether the service can take traffic, with the result of every check. Responds with
+// 503 if startup hasn't completed, the server is draining or a critical check failed.
+func HandleReadiness(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Readiness(r.Context())
+
+		if report.Status != health.StatusOK {
+			restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, report)
+			return
+		}
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,102 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"quote-service/pkg/authorclient"
+	"quote-service/pkg/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// fakeAuthorService serves the author-service endpoints used by the routes
+type fakeAuthorService struct {
+	server  *httptest.Server
+	version string
+	authors map[int]string
+	// status makes every request fail with it when it's not 0
+	status int
+
+	mu         sync.Mutex
+	requestIDs []string
+}
+
+func newFakeAuthorService(t *testing.T) *fakeAuthorService {
+	t.Helper()
+
+	s := &fakeAuthorService{version: "v1.0.0", authors: map[int]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/version", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(authorclient.VersionResponse{Version: s.version})
+	})
+	mux.HandleFunc("GET /api/authors/by-id", func(w http.ResponseWriter, r *http.Request) {
+		resp := authorclient.AuthorsResponse{Items: []authorclient.Author{}}
+		for _, idStr := range strings.Split(r.URL.Query().Get("id"), ",") {
+			id, _ := strconv.Atoi(idStr)
+			if name, ok := s.authors[id]; ok {
+				resp.Items = append(resp.Items, authorclient.Author{ID: id, Name: name})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requestIDs = append(s.requestIDs, r.Header.Get("X-Request-ID"))
+		s.mu.Unlock()
+
+		if s.status != 0 {
+			http.Error(w, http.StatusText(s.status), s.status)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+func (s *fakeAuthorService) client() *authorclient.Client {
+	return authorclient.NewClient(authorclient.NewClientConfig{BaseURL: s.server.URL, Timeout: time.Second})
+}
+
+// lastRequestID returns the X-Request-ID header of the last request
+func (s *fakeAuthorService) lastRequestID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requestIDs) == 0 {
+		return ""
+	}
+	return s.requestIDs[len(s.requestIDs)-1]
+}
+
+// newRequest creates a request carrying testTraceID, like the requests passed to handlers by the
+// trace ID middleware
+func newRequest(method, target string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	return r.WithContext(logger.WithTraceID(r.Context(), testTraceID))
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func decodeJSON[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return v
+}
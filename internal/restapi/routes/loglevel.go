@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	restapiutils "quote-service/internal/restapi/utils"
+	"quote-service/pkg/logger"
+)
+
+// maxLogLevelBodyBytes bounds the body of log level updates
+const maxLogLevelBodyBytes = 64 << 10
+
+type logLevelsResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+func newLogLevelsResponse(levels logger.LevelController) logLevelsResponse {
+	level, components := levels.Levels()
+	return logLevelsResponse{Level: level, Components: components}
+}
+
+// HandleGetLogLevels
+// /api/admin/log-level
+// Reports the base log level and the per-component overrides.
+func HandleGetLogLevels(levels logger.LevelController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		restapiutils.WriteJSONResponse(w, http.StatusOK, newLogLevelsResponse(levels))
+	}
+}
+
+// HandleSetLogLevels
+// /api/admin/log-level
+// Changes log levels without a restart, e.g. {"level": "debug", "components": {"routes": "warn"}}.
+// Both fields are optional, an empty component level removes its override.
+func HandleSetLogLevels(logger logger.Logger, levels logger.LevelController) http.HandlerFunc {
+	type Request struct {
+		Level      string            `json:"level"`
+		Components map[string]string `json:"components"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogLevelBodyBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			errorResp := requestError{
+				Error:   "Invalid request body",
+				Code:    400,
+				Details: err.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		if err := levels.SetLevels(req.Level, req.Components); err != nil {
+			errorResp := requestError{
+				Error:   "Invalid log level",
+				Code:    400,
+				Details: err.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		resp := newLogLevelsResponse(levels)
+		logger.WarnWithCtx(r.Context(), "Log levels changed", "level", resp.Level)
+		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
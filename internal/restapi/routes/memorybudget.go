@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// AdmissionReject makes requests that don't fit in the budget fail immediately
+	AdmissionReject = "reject"
+	// AdmissionQueue makes requests that don't fit wait for memory to be released
+	AdmissionQueue = "queue"
+)
+
+var (
+	ErrBudgetExceeded = errors.New("memory budget exceeded")
+	ErrBudgetTooSmall = errors.New("request is larger than the whole memory budget")
+	ErrBudgetClosed   = errors.New("memory budget closed")
+	ErrQueueTimeout   = errors.New("timed out waiting for memory budget")
+)
+
+// MemoryBudget limits the total memory held by all mock-memory allocations. A nil *MemoryBudget
+// is unlimited.
+type MemoryBudget struct {
+	limitMB      int
+	admission    string
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	usedMB int
+	closed bool
+	// released is closed and replaced whenever memory is released, waking up queued requests
+	released chan struct{}
+}
+
+// BudgetUsage is a snapshot of the memory budget
+type BudgetUsage struct {
+	LimitMB     int `json:"limit_mb"`
+	UsedMB      int `json:"used_mb"`
+	RemainingMB int `json:"remaining_mb"`
+}
+
+// NewMemoryBudget creates a budget of limitMB. admission is AdmissionReject or AdmissionQueue;
+// queued requests give up after queueTimeout.
+func NewMemoryBudget(limitMB int, admission string, queueTimeout time.Duration) *MemoryBudget {
+	return &MemoryBudget{
+		limitMB:      limitMB,
+		admission:    admission,
+		queueTimeout: queueTimeout,
+		released:     make(chan struct{}),
+	}
+}
+
+// Acquire reserves memoryMB of the budget, queueing if the admission mode allows it. Reserved
+// memory must be given back with Release.
+func (b *MemoryBudget) Acquire(ctx context.Context, memoryMB int) error {
+	if b == nil {
+		return nil
+	}
+
+	if memoryMB > b.limitMB {
+		return ErrBudgetTooSmall
+	}
+
+	var timeout <-chan time.Time
+	if b.admission == AdmissionQueue {
+		timer := time.NewTimer(b.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return ErrBudgetClosed
+		}
+		if b.usedMB+memoryMB <= b.limitMB {
+			b.usedMB += memoryMB
+			b.mu.Unlock()
+			return nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		if b.admission != AdmissionQueue {
+			return ErrBudgetExceeded
+		}
+
+		select {
+		case <-released:
+		case <-timeout:
+			return ErrQueueTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// queues reports whether requests that don't fit wait for memory to be released
+func (b *MemoryBudget) queues() bool {
+	return b != nil && b.admission == AdmissionQueue
+}
+
+// Release gives memoryMB back to the budget
+func (b *MemoryBudget) Release(memoryMB int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.usedMB -= memoryMB
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// Close rejects all queued and future requests. Used during shutdown.
+func (b *MemoryBudget) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// Usage returns the current state of the budget, nil for an unlimited budget
+func (b *MemoryBudget) Usage() *BudgetUsage {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &BudgetUsage{
+		LimitMB:     b.limitMB,
+		UsedMB:      b.usedMB,
+		RemainingMB: b.limitMB - b.usedMB,
+	}
+}
@@ -0,0 +1,98 @@
+package routes_test
+
+import (
+	"context"
+	"errors"
+	"quote-service/internal/restapi/routes"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudgetReject(t *testing.T) {
+	budget := routes.NewMemoryBudget(10, routes.AdmissionReject, 0)
+	ctx := context.Background()
+
+	if err := budget.Acquire(ctx, 11); !errors.Is(err, routes.ErrBudgetTooSmall) {
+		t.Errorf("Acquire(11) error = %v, want ErrBudgetTooSmall", err)
+	}
+	if err := budget.Acquire(ctx, 8); err != nil {
+		t.Fatalf("Acquire(8) error = %v", err)
+	}
+	if err := budget.Acquire(ctx, 3); !errors.Is(err, routes.ErrBudgetExceeded) {
+		t.Errorf("Acquire(3) error = %v, want ErrBudgetExceeded", err)
+	}
+	if usage := budget.Usage(); usage.UsedMB != 8 || usage.RemainingMB != 2 {
+		t.Errorf("Usage() = %+v, want 8 MB used", usage)
+	}
+
+	budget.Release(8)
+	if err := budget.Acquire(ctx, 3); err != nil {
+		t.Errorf("Acquire(3) after the release error = %v", err)
+	}
+}
+
+func TestMemoryBudgetQueue(t *testing.T) {
+	budget := routes.NewMemoryBudget(10, routes.AdmissionQueue, time.Second)
+	ctx := context.Background()
+	if err := budget.Acquire(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- budget.Acquire(ctx, 5) }()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("queued Acquire returned %v before memory was released", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	budget.Release(10)
+	if err := <-acquired; err != nil {
+		t.Errorf("queued Acquire error = %v", err)
+	}
+}
+
+func TestMemoryBudgetQueueEnds(t *testing.T) {
+	tests := []struct {
+		name    string
+		end     func(budget *routes.MemoryBudget, cancel context.CancelFunc)
+		wantErr error
+	}{
+		{name: "timeout", end: func(*routes.MemoryBudget, context.CancelFunc) {}, wantErr: routes.ErrQueueTimeout},
+		{name: "cancelled", end: func(_ *routes.MemoryBudget, cancel context.CancelFunc) { cancel() }, wantErr: context.Canceled},
+		{name: "closed", end: func(budget *routes.MemoryBudget, _ context.CancelFunc) { budget.Close() }, wantErr: routes.ErrBudgetClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := routes.NewMemoryBudget(10, routes.AdmissionQueue, 50*time.Millisecond)
+			if err := budget.Acquire(context.Background(), 10); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			acquired := make(chan error)
+			go func() { acquired <- budget.Acquire(ctx, 5) }()
+			tt.end(budget, cancel)
+
+			if err := <-acquired; !errors.Is(err, tt.wantErr) {
+				t.Errorf("Acquire() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryBudgetNil(t *testing.T) {
+	var budget *routes.MemoryBudget
+
+	if err := budget.Acquire(context.Background(), 1000); err != nil {
+		t.Errorf("Acquire() on a nil budget error = %v", err)
+	}
+	budget.Release(1000)
+	budget.Close()
+	if usage := budget.Usage(); usage != nil {
+		t.Errorf("Usage() = %+v, want nil", usage)
+	}
+}
@@ -0,0 +1,232 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	restapiutils "quote-service/internal/restapi/utils"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cpuLoadPeriod is the duty cycle length of a CPU burner: it spins for utilization% of each period
+// and sleeps for the rest
+const cpuLoadPeriod = 100 * time.Millisecond
+
+// Global variables to cancel CPU loads during shutdown
+var (
+	activeCPULoads = make(map[string]context.CancelCauseFunc)
+	cpuLoadsMutex  sync.Mutex
+	// cpuLoadsStopped is set by StopCPULoads, later loads are rejected
+	cpuLoadsStopped bool
+)
+
+// StopCPULoads cancels all in-flight mock-cpu loads and rejects new ones. Used during shutdown.
+func StopCPULoads() {
+	cpuLoadsMutex.Lock()
+	defer cpuLoadsMutex.Unlock()
+
+	cpuLoadsStopped = true
+	for _, cancel := range activeCPULoads {
+		cancel(errReleasedByShutdown)
+	}
+}
+
+// ActiveCPULoads returns the number of mock-cpu loads currently running
+func ActiveCPULoads() int {
+	cpuLoadsMutex.Lock()
+	defer cpuLoadsMutex.Unlock()
+
+	return len(activeCPULoads)
+}
+
+// burnCPU keeps cores goroutines busy at utilizationPercent until duration has passed or ctx is
+// cancelled. It returns the number of busy-loop iterations performed.
+func burnCPU(ctx context.Context, cores, utilizationPercent int, duration time.Duration) uint64 {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	busy := cpuLoadPeriod * time.Duration(utilizationPercent) / 100
+	var iterations atomic.Uint64
+
+	var wg sync.WaitGroup
+	for range cores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var count uint64
+			for ctx.Err() == nil {
+				periodStart := time.Now()
+				for time.Since(periodStart) < busy {
+					// Cheap arithmetic the compiler can't remove
+					count++
+					count ^= count << 13
+				}
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(cpuLoadPeriod - time.Since(periodStart)):
+				}
+			}
+			iterations.Add(count)
+		}()
+	}
+	wg.Wait()
+
+	return iterations.Load()
+}
+
+// parseIntParam reads an integer query parameter within [minValue, maxValue], returning
+// defaultValue when it is missing
+func parseIntParam(r *http.Request, name string, defaultValue, minValue, maxValue int) (int, bool) {
+	valueStr := r.URL.Query().Get(name)
+	if valueStr == "" {
+		return defaultValue, true
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < minValue || value > maxValue {
+		return 0, false
+	}
+	return value, true
+}
+
+// HandleMockCPU
+// /api/mock-cpu
+// This endpoint burns CPU for specified duration to demonstrate CPU based
+// auto-scaling behavior. Query parameters:
+//   - cores: number of cores to keep busy (1-number of CPUs, default: 1)
+//   - utilization_percent: target utilization of each core (1-100, default: 100)
+//   - duration_seconds: duration of the load in seconds (1-300, default: 10)
+//
+// The load stops early if the client disconnects or the server shuts down.
+func HandleMockCPU(logger logger.Logger) http.HandlerFunc {
+	type Response struct {
+		Message            string `json:"message"`
+		Cores              int    `json:"cores"`
+		UtilizationPercent int    `json:"utilization_percent"`
+		DurationS          int    `json:"duration_seconds"`
+		Timestamp          string `json:"timestamp"`
+		RequestID          string `json:"request_id"`
+		ActiveCPULoads     int    `json:"active_cpu_loads"`
+		Iterations         uint64 `json:"iterations"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		requestID := newRequestID()
+		maxCores := runtime.NumCPU()
+
+		cores, ok := parseIntParam(r, "cores", 1, 1, maxCores)
+		if !ok {
+			logger.WarnWithCtx(r.Context(), "Invalid cores parameter",
+				"cores", r.URL.Query().Get("cores"), "request_id", requestID)
+			errorResp := requestError{
+				Error:   "Invalid cores parameter",
+				Code:    400,
+				Details: "cores must be an integer between 1 and " + strconv.Itoa(maxCores),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		utilizationPercent, ok := parseIntParam(r, "utilization_percent", 100, 1, 100)
+		if !ok {
+			logger.WarnWithCtx(r.Context(), "Invalid utilization_percent parameter",
+				"utilization_percent", r.URL.Query().Get("utilization_percent"), "request_id", requestID)
+			errorResp := requestError{
+				Error:   "Invalid utilization_percent parameter",
+				Code:    400,
+				Details: "utilization_percent must be an integer between 1 and 100",
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		durationS, ok := parseIntParam(r, "duration_seconds", 10, 1, 300)
+		if !ok {
+			logger.WarnWithCtx(r.Context(), "Invalid duration_seconds parameter",
+				"duration_seconds", r.URL.Query().Get("duration_seconds"), "request_id", requestID)
+			errorResp := requestError{
+				Error:   "Invalid duration_seconds parameter",
+				Code:    400,
+				Details: "duration_seconds must be an integer between 1 and 300 (5 minutes)",
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		logger.InfoFields(r.Context(), "Mock-cpu endpoint called",
+			field.String("timestamp", startTime.Format(time.RFC3339)), field.String("request_id", requestID),
+			field.Int("cores", cores), field.Int("utilization_percent", utilizationPercent),
+			field.Int("duration_seconds", durationS))
+
+		// The load ends early if the client disconnects or the server shuts down
+		loadCtx, cancelLoad := context.WithCancelCause(r.Context())
+		defer cancelLoad(nil)
+
+		cpuLoadsMutex.Lock()
+		if cpuLoadsStopped {
+			cpuLoadsMutex.Unlock()
+			logger.WarnWithCtx(r.Context(), "Mock-cpu load rejected during shutdown", "request_id", requestID)
+			errorResp := requestError{
+				Error:   "Mock-cpu load cancelled",
+				Code:    503,
+				Details: errReleasedByShutdown.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, errorResp)
+			return
+		}
+		activeCPULoads[requestID] = cancelLoad
+		currentLoads := len(activeCPULoads)
+		cpuLoadsMutex.Unlock()
+
+		iterations := burnCPU(loadCtx, cores, utilizationPercent, time.Duration(durationS)*time.Second)
+
+		cpuLoadsMutex.Lock()
+		delete(activeCPULoads, requestID)
+		cpuLoadsMutex.Unlock()
+
+		if r.Context().Err() != nil {
+			logger.WarnFields(r.Context(), "Client disconnected, mock-cpu load stopped early",
+				field.String("request_id", requestID), field.Int64("ran_ms", time.Since(startTime).Milliseconds()))
+			return
+		}
+
+		if cause := context.Cause(loadCtx); cause != nil && cause != context.DeadlineExceeded {
+			logger.WarnFields(r.Context(), "Mock-cpu load stopped early",
+				field.String("request_id", requestID), field.String("reason", cause.Error()),
+				field.Int64("ran_ms", time.Since(startTime).Milliseconds()))
+			errorResp := requestError{
+				Error:   "Mock-cpu load cancelled",
+				Code:    503,
+				Details: cause.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, errorResp)
+			return
+		}
+
+		duration := time.Since(startTime)
+		logger.InfoFields(r.Context(), "Mock-cpu demo completed",
+			field.String("request_id", requestID), field.Int64("duration_ms", duration.Milliseconds()),
+			field.Uint64("iterations", iterations))
+
+		resp := Response{
+			Message:            "Mock-cpu demo completed successfully",
+			Cores:              cores,
+			UtilizationPercent: utilizationPercent,
+			DurationS:          durationS,
+			Timestamp:          startTime.Format(time.RFC3339),
+			RequestID:          requestID,
+			ActiveCPULoads:     currentLoads,
+			Iterations:         iterations,
+		}
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
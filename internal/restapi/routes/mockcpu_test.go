@@ -0,0 +1,81 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger/testlogger"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestHandleMockCPUParams(t *testing.T) {
+	maxCores := strconv.Itoa(runtime.NumCPU())
+
+	tests := []struct {
+		query     string
+		wantError string
+	}{
+		{query: "cores=0", wantError: "Invalid cores parameter"},
+		{query: "cores=1"},
+		{query: "cores=" + maxCores},
+		{query: "cores=" + strconv.Itoa(runtime.NumCPU()+1), wantError: "Invalid cores parameter"},
+		{query: "cores=abc", wantError: "Invalid cores parameter"},
+		{query: "utilization_percent=0", wantError: "Invalid utilization_percent parameter"},
+		{query: "utilization_percent=1"},
+		{query: "utilization_percent=100"},
+		{query: "utilization_percent=101", wantError: "Invalid utilization_percent parameter"},
+		{query: "duration_seconds=0", wantError: "Invalid duration_seconds parameter"},
+		{query: "duration_seconds=1"},
+		{query: "duration_seconds=300"},
+		{query: "duration_seconds=301", wantError: "Invalid duration_seconds parameter"},
+		{query: "duration_seconds=1.5", wantError: "Invalid duration_seconds parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			log := testlogger.New()
+
+			// Accepted loads stop right away, as if the client had disconnected
+			r := newRequest(http.MethodGet, "/api/mock-cpu?"+tt.query)
+			ctx, cancel := context.WithCancel(r.Context())
+			cancel()
+			w := serve(routes.HandleMockCPU(log), r.WithContext(ctx))
+
+			if tt.wantError == "" {
+				if _, ok := log.Find(testlogger.LevelWarn, "Client disconnected, mock-cpu load stopped early"); !ok {
+					t.Errorf("load wasn't started, status %d, logs:\n%s", w.Code, log)
+				}
+				return
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if resp := decodeJSON[map[string]any](t, w); resp["error"] != tt.wantError {
+				t.Errorf("error = %v, want %q", resp["error"], tt.wantError)
+			}
+			if _, ok := log.Find(testlogger.LevelWarn, tt.wantError); !ok {
+				t.Errorf("no warning %q in:\n%s", tt.wantError, log)
+			}
+		})
+	}
+}
+
+func TestHandleMockCPUAfterStop(t *testing.T) {
+	routes.StopCPULoads()
+	t.Cleanup(routes.ResetCPULoads)
+
+	log := testlogger.New()
+	w := serve(routes.HandleMockCPU(log), newRequest(http.MethodGet, "/api/mock-cpu?duration_seconds=1"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if _, ok := log.Find(testlogger.LevelWarn, "Mock-cpu load rejected during shutdown"); !ok {
+		t.Errorf("no rejection warning in:\n%s", log)
+	}
+	if n := routes.ActiveCPULoads(); n != 0 {
+		t.Errorf("ActiveCPULoads() = %d, want 0", n)
+	}
+}
@@ -1,172 +1,456 @@
 package routes
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"net/http"
+	"quote-service/internal/memstats"
 	restapiutils "quote-service/internal/restapi/utils"
 	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// memoryBlockSize is the size of the blocks allocations are made of, so scenarios can grow and
+// shrink them in 1 MB steps
+const memoryBlockSize = 1024 * 1024
+
+// States of a mock-memory allocation. Synchronous requests go from allocating to holding, async
+// jobs may also be queued for the memory budget and are kept in finishedJobs once done.
+const (
+	JobStateQueued     = "queued"
+	JobStateAllocating = "allocating"
+	JobStateHolding    = "holding"
+	JobStateCompleted  = "completed"
+	JobStateCancelled  = "cancelled"
+	JobStateFailed     = "failed"
+)
+
+var (
+	// errReleasedByShutdown and errReleasedByRequest are the cancellation causes of holds that
+	// end before their duration has passed
+	errReleasedByShutdown = errors.New("the server is shutting down")
+	errReleasedByRequest  = errors.New("released by request")
+)
+
+// requestError is the error response of the mock endpoints
+type requestError struct {
+	Error   string       `json:"error"`
+	Code    int          `json:"code"`
+	Details string       `json:"details,omitempty"`
+	Budget  *BudgetUsage `json:"budget,omitempty"`
+}
+
+// allocation is a block of memory held by a mock-memory request or job. cancel releases it
+// before the requested duration has passed.
+type allocation struct {
+	id        string
+	async     bool
+	memoryMB  int
+	durationS int
+	createdAt time.Time
+	cancel    context.CancelCauseFunc
+	// done is closed once the allocation is released and removed from activeAllocations
+	done chan struct{}
+	// holdsJobSlot is set for async jobs, whose slot is given back by finishJob
+	holdsJobSlot bool
+
+	// Fields below are guarded by allocationsMutex
+	data       [][]byte
+	state      string
+	holdStart  time.Time
+	finishedAt time.Time
+	err        string
+	// memoryAfter is the process memory right after the allocation was made
+	memoryAfter *memstats.Stats
+}
+
 // Global variables to hold memory allocations during request processing
 var (
-	activeAllocations = make(map[string][]byte)
+	activeAllocations = make(map[string]*allocation)
 	allocationsMutex  sync.Mutex
+	// allocationsStopped is set by ReleaseActiveAllocations, later allocations are cancelled as
+	// soon as they're registered
+	allocationsStopped bool
+
+	// lastRequestID numbers mock requests, so concurrent requests never share an ID and overwrite
+	// each other in activeAllocations
+	lastRequestID atomic.Uint64
 )
 
+// newRequestID returns the next unique ID for a mock-memory, mock-cpu or scenario request
+func newRequestID() string {
+	return strconv.FormatUint(lastRequestID.Add(1), 10)
+}
+
+// compareRequests orders requests by creation time, then by ID so requests created at the same
+// time keep the order of newRequestID
+func compareRequests(aCreatedAt time.Time, aID string, bCreatedAt time.Time, bID string) int {
+	if c := aCreatedAt.Compare(bCreatedAt); c != 0 {
+		return c
+	}
+
+	aNum, aErr := strconv.ParseUint(aID, 10, 64)
+	bNum, bErr := strconv.ParseUint(bID, 10, 64)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(aID, bID)
+	}
+	return cmp.Compare(aNum, bNum)
+}
+
+// ReleaseActiveAllocations cancels all in-flight mock-memory holds so their requests finish
+// immediately, and cancels those registered later, e.g. by requests that were waiting for the
+// memory budget. Used during shutdown so draining doesn't wait for holds of up to 5 minutes.
+func ReleaseActiveAllocations() {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	allocationsStopped = true
+	for _, allocation := range activeAllocations {
+		allocation.cancel(errReleasedByShutdown)
+	}
+}
+
+// ActiveAllocations returns the number of mock-memory allocations currently held
+func ActiveAllocations() int {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	return len(activeAllocations)
+}
+
+// ActiveAllocationBytes returns the total size of the mock-memory allocations currently held
+func ActiveAllocationBytes() int {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	total := 0
+	for _, allocation := range activeAllocations {
+		total += len(allocation.data) * memoryBlockSize
+	}
+	return total
+}
+
+// registerAllocation stores a in the global map and returns the number of active allocations. a is
+// cancelled right away once ReleaseActiveAllocations has been called.
+func registerAllocation(a *allocation) int {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	if allocationsStopped {
+		a.cancel(errReleasedByShutdown)
+	}
+	activeAllocations[a.id] = a
+	return len(activeAllocations)
+}
+
+// unregisterAllocation removes a from the global map, recording its final state
+func unregisterAllocation(a *allocation, state string) {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	delete(activeAllocations, a.id)
+	a.state = state
+	a.finishedAt = time.Now()
+	a.data = nil
+	close(a.done)
+}
+
+// setAllocationState updates the state of a
+func setAllocationState(a *allocation, state string) {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	a.state = state
+	if state == JobStateHolding {
+		a.holdStart = time.Now()
+	}
+}
+
+// allocateBlocks allocates memoryMB MB in memoryBlockSize blocks and fills them so the pages are
+// actually backed by memory
+func allocateBlocks(memoryMB int) [][]byte {
+	blocks := make([][]byte, memoryMB)
+	for b := range blocks {
+		block := make([]byte, memoryBlockSize) // Dynamic memory allocation
+
+		// Fill memory with patterns to prevent optimization and ensure allocation
+		for i := range block {
+			block[i] = byte(i % 256)
+		}
+		blocks[b] = block
+	}
+	return blocks
+}
+
+// parseMockMemoryParams reads and validates memory_mb and duration_seconds from the query string
+func parseMockMemoryParams(r *http.Request, logger logger.Logger, requestID string) (int, int, *requestError) {
+	// Parse query parameters
+	memoryMBStr := r.URL.Query().Get("memory_mb")
+	durationSecondsStr := r.URL.Query().Get("duration_seconds")
+
+	// Set defaults
+	memoryMB := 10  // Default 10MB
+	durationS := 10 // Default 10 seconds
+
+	var err error
+
+	// Parse and validate memory_mb parameter
+	if memoryMBStr != "" {
+		memoryMB, err = strconv.Atoi(memoryMBStr)
+		if err != nil || memoryMB <= 0 {
+			logger.WarnFields(r.Context(), "Invalid memory_mb parameter",
+				field.String("memory_mb", memoryMBStr), field.String("request_id", requestID))
+			return 0, 0, &requestError{
+				Error:   "Invalid memory_mb parameter",
+				Code:    400,
+				Details: "memory_mb must be a positive integer",
+			}
+		}
+		if memoryMB > 1000 { // Limit to 1GB max
+			logger.WarnFields(r.Context(), "Memory limit exceeded",
+				field.Int("memory_mb", memoryMB), field.String("request_id", requestID))
+			return 0, 0, &requestError{
+				Error:   "Memory limit exceeded",
+				Code:    400,
+				Details: "memory_mb cannot exceed 1000MB (1GB)",
+			}
+		}
+	}
+
+	// Parse and validate duration_seconds parameter
+	if durationSecondsStr != "" {
+		durationS, err = strconv.Atoi(durationSecondsStr)
+		if err != nil || durationS <= 0 {
+			logger.WarnFields(r.Context(), "Invalid duration_seconds parameter",
+				field.String("duration_seconds", durationSecondsStr), field.String("request_id", requestID))
+			return 0, 0, &requestError{
+				Error:   "Invalid duration_seconds parameter",
+				Code:    400,
+				Details: "duration_seconds must be a positive integer",
+			}
+		}
+		if durationS > 300 { // Limit to 5 minutes max
+			logger.WarnFields(r.Context(), "Duration limit exceeded",
+				field.Int("duration_seconds", durationS), field.String("request_id", requestID))
+			return 0, 0, &requestError{
+				Error:   "Duration limit exceeded",
+				Code:    400,
+				Details: "duration_seconds cannot exceed 300 (5 minutes)",
+			}
+		}
+	}
+
+	return memoryMB, durationS, nil
+}
+
+// allocateAndHold allocates the memory of a and holds it until its duration has passed or ctx is
+// cancelled. It returns the cancellation cause if the hold ended early and nil otherwise. The
+// caller removes a from the global map afterwards.
+func allocateAndHold(ctx context.Context, a *allocation, logger logger.Logger) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	// Allocate memory based on parameter
+	logger.InfoFields(ctx, "Allocating memory for mock-memory demo",
+		field.String("request_id", a.id), field.Int("memory_mb", a.memoryMB))
+
+	memoryAllocation := allocateBlocks(a.memoryMB)
+
+	memoryAfter := memstats.Read()
+
+	// Store allocation in global map to prevent GC
+	allocationsMutex.Lock()
+	a.data = memoryAllocation
+	a.memoryAfter = &memoryAfter
+	currentAllocations := len(activeAllocations)
+	allocationsMutex.Unlock()
+
+	// Perform CPU work to simulate load
+	var checksum uint64
+	for i := 0; i < 1000000; i++ {
+		block := memoryAllocation[i%len(memoryAllocation)]
+		checksum += uint64(block[i%len(block)])
+	}
+
+	logger.DebugFields(ctx, "Memory checksum", field.String("request_id", a.id), field.Uint64("checksum", checksum))
+
+	// Hold the memory for specified duration while doing periodic work
+	logger.InfoFields(ctx, "Holding memory for specified duration",
+		field.String("request_id", a.id), field.Int("active_allocations", currentAllocations),
+		field.Int("duration_seconds", a.durationS))
+	setAllocationState(a, JobStateHolding)
+
+	// Periodic work to keep memory active
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	done := make(chan bool, 1)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				// Access memory periodically to keep it active
+				_ = memoryAllocation[0][0]
+				_ = memoryAllocation[len(memoryAllocation)-1][memoryBlockSize-1]
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	holdTimer := time.NewTimer(time.Duration(a.durationS) * time.Second)
+	defer holdTimer.Stop()
+
+	var releaseCause error
+	select {
+	case <-holdTimer.C:
+	case <-ctx.Done():
+		releaseCause = context.Cause(ctx)
+	}
+	done <- true
+
+	return releaseCause
+}
+
+// HandleMockMemoryStats
+// /api/mock-memory/stats
+// Reports the memory the process actually holds (Go heap, RSS and the cgroup limit and usage)
+// next to the mock-memory allocations and budget, to show how close the pod is to its limit.
+func HandleMockMemoryStats(budget *MemoryBudget) http.HandlerFunc {
+	type Response struct {
+		Memory                memstats.Stats `json:"memory"`
+		ActiveAllocations     int            `json:"active_allocations"`
+		ActiveAllocationBytes int            `json:"active_allocation_bytes"`
+		Budget                *BudgetUsage   `json:"budget,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := Response{
+			Memory:                memstats.Read(),
+			ActiveAllocations:     ActiveAllocations(),
+			ActiveAllocationBytes: ActiveAllocationBytes(),
+			Budget:                budget.Usage(),
+		}
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
 // HandleAutoScalingDemo
 // /api/mock-memory
 // This endpoint allocates dynamic memory and holds it for specified duration
 // to demonstrate auto-scaling behavior. Query parameters:
 //   - memory_mb: memory to allocate in MB (1-1000, default: 10)
 //   - duration_seconds: duration to hold memory in seconds (1-300, default: 10)
-func HandleAutoScalingDemo(logger logger.Logger) http.HandlerFunc {
+//
+// Requests that would exceed the global memory budget are rejected with 503 or queued,
+// depending on the budget's admission mode. A nil budget is unlimited.
+func HandleAutoScalingDemo(logger logger.Logger, budget *MemoryBudget) http.HandlerFunc {
 	type Response struct {
-		Message           string `json:"message"`
-		MemoryMB          int    `json:"memory_mb"`
-		DurationS         int    `json:"duration_seconds"`
-		Timestamp         string `json:"timestamp"`
-		RequestID         string `json:"request_id"`
-		ActiveAllocations int    `json:"active_allocations"`
-	}
-
-	type RequestError struct {
-		Error   string `json:"error"`
-		Code    int    `json:"code"`
-		Details string `json:"details,omitempty"`
+		Message           string          `json:"message"`
+		MemoryMB          int             `json:"memory_mb"`
+		DurationS         int             `json:"duration_seconds"`
+		Timestamp         string          `json:"timestamp"`
+		RequestID         string          `json:"request_id"`
+		ActiveAllocations int             `json:"active_allocations"`
+		Budget            *BudgetUsage    `json:"budget,omitempty"`
+		MemoryBefore      memstats.Stats  `json:"memory_before"`
+		MemoryAfter       *memstats.Stats `json:"memory_after,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
-		requestID := strconv.FormatInt(startTime.UnixNano(), 10)
-
-		// Parse query parameters
-		memoryMBStr := r.URL.Query().Get("memory_mb")
-		durationSecondsStr := r.URL.Query().Get("duration_seconds")
-
-		// Set defaults
-		memoryMB := 10  // Default 10MB
-		durationS := 10 // Default 10 seconds
-
-		var err error
-
-		// Parse and validate memory_mb parameter
-		if memoryMBStr != "" {
-			memoryMB, err = strconv.Atoi(memoryMBStr)
-			if err != nil || memoryMB <= 0 {
-				logger.WarnWithCtx(r.Context(), "Invalid memory_mb parameter",
-					"memory_mb", memoryMBStr, "request_id", requestID)
-				errorResp := RequestError{
-					Error:   "Invalid memory_mb parameter",
-					Code:    400,
-					Details: "memory_mb must be a positive integer",
-				}
-				restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
-				return
-			}
-			if memoryMB > 1000 { // Limit to 1GB max
-				logger.WarnWithCtx(r.Context(), "Memory limit exceeded",
-					"memory_mb", strconv.Itoa(memoryMB), "request_id", requestID)
-				errorResp := RequestError{
-					Error:   "Memory limit exceeded",
-					Code:    400,
-					Details: "memory_mb cannot exceed 1000MB (1GB)",
-				}
-				restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
-				return
-			}
+		requestID := newRequestID()
+
+		memoryMB, durationS, reqErr := parseMockMemoryParams(r, logger, requestID)
+		if reqErr != nil {
+			restapiutils.WriteJSONResponse(w, reqErr.Code, reqErr)
+			return
 		}
 
-		// Parse and validate duration_seconds parameter
-		if durationSecondsStr != "" {
-			durationS, err = strconv.Atoi(durationSecondsStr)
-			if err != nil || durationS <= 0 {
-				logger.WarnWithCtx(r.Context(), "Invalid duration_seconds parameter",
-					"duration_seconds", durationSecondsStr, "request_id", requestID)
-				errorResp := RequestError{
-					Error:   "Invalid duration_seconds parameter",
-					Code:    400,
-					Details: "duration_seconds must be a positive integer",
-				}
-				restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+		// Log the start of autoscaling demo
+		logger.InfoFields(r.Context(), "Mock-memory endpoint called",
+			field.String("timestamp", startTime.Format(time.RFC3339)), field.String("request_id", requestID),
+			field.Int("memory_mb", memoryMB), field.Int("duration_seconds", durationS))
+
+		// Reserve the memory in the global budget before allocating it
+		if err := budget.Acquire(r.Context(), memoryMB); err != nil {
+			if r.Context().Err() != nil {
+				logger.WarnFields(r.Context(), "Client disconnected while waiting for memory budget",
+					field.String("request_id", requestID))
 				return
 			}
-			if durationS > 300 { // Limit to 5 minutes max
-				logger.WarnWithCtx(r.Context(), "Duration limit exceeded",
-					"duration_seconds", strconv.Itoa(durationS), "request_id", requestID)
-				errorResp := RequestError{
-					Error:   "Duration limit exceeded",
-					Code:    400,
-					Details: "duration_seconds cannot exceed 300 (5 minutes)",
-				}
-				restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
-				return
+
+			logger.WarnFields(r.Context(), "Mock-memory request not admitted",
+				field.String("request_id", requestID), field.Int("memory_mb", memoryMB), field.Err(err))
+			errorResp := requestError{
+				Error:   "Memory budget exceeded",
+				Code:    503,
+				Details: err.Error(),
+				Budget:  budget.Usage(),
 			}
+			restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, errorResp)
+			return
 		}
+		defer budget.Release(memoryMB)
+		budgetUsage := budget.Usage()
 
-		// Log the start of autoscaling demo
-		logger.InfoWithCtx(r.Context(), "Mock-memory endpoint called",
-			"timestamp", startTime.Format(time.RFC3339), "request_id", requestID,
-			"memory_mb", strconv.Itoa(memoryMB), "duration_seconds", strconv.Itoa(durationS))
-
-		// Allocate memory based on parameter
-		logger.InfoWithCtx(r.Context(), "Allocating memory for mock-memory demo",
-			"request_id", requestID, "memory_mb", strconv.Itoa(memoryMB))
+		// The hold ends early if the client disconnects or the allocation is released during
+		// shutdown or through the jobs API
+		holdCtx, cancelHold := context.WithCancelCause(r.Context())
+		defer cancelHold(nil)
 
-		memoryAllocation := make([]byte, memoryMB*1024*1024) // Dynamic memory allocation
-
-		// Fill memory with patterns to prevent optimization and ensure allocation
-		for i := 0; i < len(memoryAllocation); i += 4096 { // Page-sized chunks
-			for j := 0; j < 4096 && (i+j) < len(memoryAllocation); j++ {
-				memoryAllocation[i+j] = byte((i + j) % 256)
-			}
+		alloc := &allocation{
+			id:        requestID,
+			memoryMB:  memoryMB,
+			durationS: durationS,
+			createdAt: startTime,
+			cancel:    cancelHold,
+			done:      make(chan struct{}),
+			state:     JobStateAllocating,
 		}
+		currentAllocations := registerAllocation(alloc)
 
-		// Store allocation in global map to prevent GC
-		allocationsMutex.Lock()
-		activeAllocations[requestID] = memoryAllocation
-		currentAllocations := len(activeAllocations)
-		allocationsMutex.Unlock()
+		memoryBefore := memstats.Read()
+		releaseCause := allocateAndHold(holdCtx, alloc, logger)
 
-		// Perform CPU work to simulate load
-		var checksum uint64
-		for i := 0; i < 1000000; i++ {
-			checksum += uint64(memoryAllocation[i%len(memoryAllocation)])
+		// Clean up: remove from global map
+		if releaseCause != nil {
+			unregisterAllocation(alloc, JobStateCancelled)
+		} else {
+			unregisterAllocation(alloc, JobStateCompleted)
 		}
 
-		logger.Debug("Memory checksum: " + strconv.FormatUint(checksum, 10))
-
-		// Hold the memory for specified duration while doing periodic work
-		logger.InfoWithCtx(r.Context(), "Holding memory for specified duration",
-			"request_id", requestID, "active_allocations", strconv.Itoa(currentAllocations),
-			"duration_seconds", strconv.Itoa(durationS))
-
-		// Periodic work to keep memory active
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		done := make(chan bool, 1)
-		go func() {
-			for {
-				select {
-				case <-ticker.C:
-					// Access memory periodically to keep it active
-					_ = memoryAllocation[0]
-					_ = memoryAllocation[len(memoryAllocation)-1]
-				case <-done:
-					return
-				}
-			}
-		}()
+		if r.Context().Err() != nil {
+			logger.WarnFields(r.Context(), "Client disconnected, mock-memory released early",
+				field.String("request_id", requestID), field.Int64("held_ms", time.Since(startTime).Milliseconds()))
+			return
+		}
 
-		time.Sleep(time.Duration(durationS) * time.Second)
-		done <- true
+		if releaseCause != nil {
+			logger.WarnFields(r.Context(), "Mock-memory released early",
+				field.String("request_id", requestID), field.String("reason", releaseCause.Error()),
+				field.Int64("held_ms", time.Since(startTime).Milliseconds()))
 
-		// Clean up: remove from global map
-		allocationsMutex.Lock()
-		delete(activeAllocations, requestID)
-		allocationsMutex.Unlock()
+			statusCode := http.StatusServiceUnavailable
+			if errors.Is(releaseCause, errReleasedByRequest) {
+				statusCode = http.StatusConflict
+			}
+			errorResp := requestError{
+				Error:   "Mock-memory hold cancelled",
+				Code:    statusCode,
+				Details: releaseCause.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, statusCode, errorResp)
+			return
+		}
 
 		// Force a small GC cycle by creating temporary allocation
 		tempMemory := make([]byte, 1024) // 1KB
@@ -176,9 +460,9 @@ func HandleAutoScalingDemo(logger logger.Logger) http.HandlerFunc {
 		tempMemory = nil // This can be GC'd
 
 		duration := time.Since(startTime)
-		logger.InfoWithCtx(r.Context(), "Mock-memory demo completed",
-			"request_id", requestID, "duration_ms", strconv.FormatInt(duration.Milliseconds(), 10),
-			"active_allocations", strconv.Itoa(currentAllocations-1))
+		logger.InfoFields(r.Context(), "Mock-memory demo completed",
+			field.String("request_id", requestID), field.Int64("duration_ms", duration.Milliseconds()),
+			field.Int("active_allocations", currentAllocations-1))
 
 		resp := Response{
 			Message:           "Mock-memory demo completed successfully",
@@ -187,6 +471,9 @@ func HandleAutoScalingDemo(logger logger.Logger) http.HandlerFunc {
 			Timestamp:         startTime.Format(time.RFC3339),
 			RequestID:         requestID,
 			ActiveAllocations: currentAllocations,
+			Budget:            budgetUsage,
+			MemoryBefore:      memoryBefore,
+			MemoryAfter:       alloc.memoryAfter,
 		}
 
 		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
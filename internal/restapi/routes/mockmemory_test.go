@@ -0,0 +1,115 @@
+package routes_test
+
+import (
+	"net/http"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+	"time"
+)
+
+func TestHandleAutoScalingDemoInvalidParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantLog string
+	}{
+		{query: "memory_mb=abc", wantLog: "Invalid memory_mb parameter"},
+		{query: "memory_mb=0", wantLog: "Invalid memory_mb parameter"},
+		{query: "memory_mb=1001", wantLog: "Memory limit exceeded"},
+		{query: "duration_seconds=-1", wantLog: "Invalid duration_seconds parameter"},
+		{query: "duration_seconds=301", wantLog: "Duration limit exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			log := testlogger.New()
+			handler := routes.HandleAutoScalingDemo(log, nil)
+
+			w := serve(handler, newRequest(http.MethodGet, "/api/mock-memory?"+tt.query))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			resp := decodeJSON[map[string]any](t, w)
+			if resp["error"] != tt.wantLog {
+				t.Errorf("error = %v, want %q", resp["error"], tt.wantLog)
+			}
+
+			entry, ok := log.Find(testlogger.LevelWarn, tt.wantLog)
+			if !ok {
+				t.Fatalf("no warning %q in:\n%s", tt.wantLog, log)
+			}
+			if entry.TraceID != testTraceID || entry.String("request_id") == "" {
+				t.Errorf("unexpected entry %+v", entry)
+			}
+		})
+	}
+}
+
+func TestHandleAutoScalingDemoBudgetExceeded(t *testing.T) {
+	log := testlogger.New()
+	budget := routes.NewMemoryBudget(1, routes.AdmissionReject, 0)
+	handler := routes.HandleAutoScalingDemo(log, budget)
+
+	w := serve(handler, newRequest(http.MethodGet, "/api/mock-memory?memory_mb=2&duration_seconds=1"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	entry, ok := log.Find(testlogger.LevelWarn, "Mock-memory request not admitted")
+	if !ok {
+		t.Fatalf("rejection not logged:\n%s", log)
+	}
+	if got, _ := entry.Attr("memory_mb"); got != int64(2) {
+		t.Errorf("memory_mb = %v, want 2", got)
+	}
+	if got := entry.String("error"); got != routes.ErrBudgetTooSmall.Error() {
+		t.Errorf("error = %q, want %q", got, routes.ErrBudgetTooSmall)
+	}
+}
+
+func TestHandleAutoScalingDemo(t *testing.T) {
+	log := testlogger.New()
+	handler := routes.HandleAutoScalingDemo(log, routes.NewMemoryBudget(10, routes.AdmissionReject, 0))
+
+	w := serve(handler, newRequest(http.MethodGet, "/api/mock-memory?memory_mb=1&duration_seconds=1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusOK, w.Body.String())
+	}
+	resp := decodeJSON[map[string]any](t, w)
+	if resp["memory_mb"] != 1.0 || resp["duration_seconds"] != 1.0 {
+		t.Errorf("unexpected response %v", resp)
+	}
+
+	if !log.Has(testlogger.LevelInfo, "Mock-memory endpoint called") {
+		t.Errorf("request not logged:\n%s", log)
+	}
+	if warnings := log.ByLevel(testlogger.LevelWarn); len(warnings) != 0 {
+		t.Errorf("unexpected warnings:\n%s", log)
+	}
+	if routes.ActiveAllocations() != 0 {
+		t.Errorf("allocation still active after the response")
+	}
+}
+
+func TestHandleAutoScalingDemoAfterRelease(t *testing.T) {
+	routes.ReleaseActiveAllocations()
+	t.Cleanup(routes.ResetAllocations)
+
+	log := testlogger.New()
+	handler := routes.HandleAutoScalingDemo(log, nil)
+
+	start := time.Now()
+	w := serve(handler, newRequest(http.MethodGet, "/api/mock-memory?memory_mb=1&duration_seconds=5"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusServiceUnavailable, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v, want it to be released right away", elapsed)
+	}
+	if _, ok := log.Find(testlogger.LevelInfo, "Allocating memory for mock-memory demo"); ok {
+		t.Error("memory was allocated after ReleaseActiveAllocations")
+	}
+	if n := routes.ActiveAllocations(); n != 0 {
+		t.Errorf("ActiveAllocations() = %d, want 0", n)
+	}
+}
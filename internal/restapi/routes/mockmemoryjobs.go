@@ -0,0 +1,364 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	restapiutils "quote-service/internal/restapi/utils"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// jobRetention is how long finished async jobs can still be looked up
+	jobRetention = 10 * time.Minute
+	// jobReleaseWait is how long a DELETE waits for the released allocation to be freed
+	jobReleaseWait = 5 * time.Second
+)
+
+// ErrTooManyJobs is returned when the maximum number of unfinished async jobs is reached
+var ErrTooManyJobs = errors.New("too many mock-memory jobs")
+
+var (
+	// finishedJobs keeps async jobs for jobRetention after they finish. Guarded by
+	// allocationsMutex.
+	finishedJobs = make(map[string]*allocation)
+	// unfinishedJobs counts the queued and running async jobs. Guarded by allocationsMutex.
+	unfinishedJobs int
+)
+
+// acquireJobSlot counts a new async job, unless maxJobs are already unfinished. maxJobs <= 0 means
+// no limit. The slot is given back by finishJob, or with releaseJobSlot if the job never starts.
+func acquireJobSlot(maxJobs int) bool {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	if maxJobs > 0 && unfinishedJobs >= maxJobs {
+		return false
+	}
+	unfinishedJobs++
+	return true
+}
+
+func releaseJobSlot() {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	unfinishedJobs--
+}
+
+// JobStatus describes a mock-memory allocation, either a synchronous request or an async job
+type JobStatus struct {
+	ID              string  `json:"id"`
+	Async           bool    `json:"async"`
+	State           string  `json:"state"`
+	MemoryMB        int     `json:"memory_mb"`
+	DurationS       int     `json:"duration_seconds"`
+	CreatedAt       string  `json:"created_at"`
+	HeldSeconds     float64 `json:"held_seconds"`
+	ProgressPercent float64 `json:"progress_percent"`
+	FinishedAt      string  `json:"finished_at,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// status returns the JobStatus of a. The caller must hold allocationsMutex.
+func (a *allocation) status(now time.Time) JobStatus {
+	status := JobStatus{
+		ID:        a.id,
+		Async:     a.async,
+		State:     a.state,
+		MemoryMB:  a.memoryMB,
+		DurationS: a.durationS,
+		CreatedAt: a.createdAt.Format(time.RFC3339),
+		Error:     a.err,
+	}
+
+	if !a.finishedAt.IsZero() {
+		status.FinishedAt = a.finishedAt.Format(time.RFC3339)
+		now = a.finishedAt
+	}
+
+	if !a.holdStart.IsZero() {
+		held := now.Sub(a.holdStart).Seconds()
+		status.HeldSeconds = held
+		status.ProgressPercent = min(100, held/float64(a.durationS)*100)
+	}
+	if a.state == JobStateCompleted {
+		status.ProgressPercent = 100
+	}
+
+	return status
+}
+
+// finishJob moves an async job from activeAllocations to finishedJobs
+func finishJob(a *allocation, state string, err error) {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	now := time.Now()
+	a.state = state
+	a.finishedAt = now
+	a.data = nil
+	if err != nil {
+		a.err = err.Error()
+	}
+
+	delete(activeAllocations, a.id)
+	finishedJobs[a.id] = a
+	if a.holdsJobSlot {
+		unfinishedJobs--
+	}
+	close(a.done)
+
+	for id, job := range finishedJobs {
+		if now.Sub(job.finishedAt) > jobRetention {
+			delete(finishedJobs, id)
+		}
+	}
+}
+
+// lookupJob returns the status of an active allocation or a finished job
+func lookupJob(id string) (JobStatus, bool) {
+	allocationsMutex.Lock()
+	defer allocationsMutex.Unlock()
+
+	if a, ok := activeAllocations[id]; ok {
+		return a.status(time.Now()), true
+	}
+	if a, ok := finishedJobs[id]; ok {
+		return a.status(time.Now()), true
+	}
+
+	return JobStatus{}, false
+}
+
+// runJob waits for the memory budget if needed, then allocates and holds the memory of an
+// async job
+func runJob(ctx context.Context, a *allocation, budget *MemoryBudget, reserved bool, logger logger.Logger) {
+	if !reserved {
+		if err := budget.Acquire(ctx, a.memoryMB); err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				err = cause
+			}
+			logger.WarnFields(ctx, "Mock-memory job not admitted", field.String("request_id", a.id), field.Err(err))
+			finishJob(a, jobEndState(err), err)
+			return
+		}
+	}
+	defer budget.Release(a.memoryMB)
+
+	setAllocationState(a, JobStateAllocating)
+	startTime := time.Now()
+
+	if cause := allocateAndHold(ctx, a, logger); cause != nil {
+		logger.WarnFields(ctx, "Mock-memory job released early", field.String("request_id", a.id),
+			field.String("reason", cause.Error()), field.Int64("held_ms", time.Since(startTime).Milliseconds()))
+		finishJob(a, jobEndState(cause), cause)
+		return
+	}
+
+	logger.InfoFields(ctx, "Mock-memory job completed", field.String("request_id", a.id),
+		field.Int64("duration_ms", time.Since(startTime).Milliseconds()))
+	finishJob(a, JobStateCompleted, nil)
+}
+
+// jobEndState maps the reason a job ended early to its final state
+func jobEndState(err error) string {
+	if errors.Is(err, errReleasedByRequest) || errors.Is(err, errReleasedByShutdown) {
+		return JobStateCancelled
+	}
+	return JobStateFailed
+}
+
+// HandleCreateMockMemoryJob
+// POST /api/mock-memory/jobs
+// Starts an asynchronous mock-memory allocation and returns its job ID immediately. Takes the same
+// query parameters as /api/mock-memory. When the memory budget queues requests the job starts in
+// the "queued" state, otherwise requests that don't fit are rejected with 503. Jobs outlive their
+// request and so escape the concurrency cap of expensive routes, at most maxJobs (0 for no limit)
+// can be queued or running at a time.
+func HandleCreateMockMemoryJob(logger logger.Logger, budget *MemoryBudget, maxJobs int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		requestID := newRequestID()
+
+		memoryMB, durationS, reqErr := parseMockMemoryParams(r, logger, requestID)
+		if reqErr != nil {
+			restapiutils.WriteJSONResponse(w, reqErr.Code, reqErr)
+			return
+		}
+
+		if !acquireJobSlot(maxJobs) {
+			logger.WarnFields(r.Context(), "Mock-memory job not admitted",
+				field.String("request_id", requestID), field.Int("max_jobs", maxJobs), field.Err(ErrTooManyJobs))
+			errorResp := requestError{
+				Error:   "Too many mock-memory jobs",
+				Code:    503,
+				Details: ErrTooManyJobs.Error(),
+			}
+			w.Header().Set("Retry-After", "1")
+			restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, errorResp)
+			return
+		}
+
+		// Without queueing the budget is checked now so the client gets the rejection directly
+		reserved := false
+		if !budget.queues() {
+			if err := budget.Acquire(r.Context(), memoryMB); err != nil {
+				releaseJobSlot()
+				logger.WarnFields(r.Context(), "Mock-memory job not admitted",
+					field.String("request_id", requestID), field.Int("memory_mb", memoryMB), field.Err(err))
+				errorResp := requestError{
+					Error:   "Memory budget exceeded",
+					Code:    503,
+					Details: err.Error(),
+					Budget:  budget.Usage(),
+				}
+				restapiutils.WriteJSONResponse(w, http.StatusServiceUnavailable, errorResp)
+				return
+			}
+			reserved = true
+		}
+
+		// The job outlives the request, it keeps the request's values (like the trace ID) but not
+		// its cancellation
+		ctx, cancel := context.WithCancelCause(context.WithoutCancel(r.Context()))
+
+		state := JobStateAllocating
+		if !reserved {
+			state = JobStateQueued
+		}
+		alloc := &allocation{
+			id:           requestID,
+			async:        true,
+			holdsJobSlot: true,
+			memoryMB:     memoryMB,
+			durationS:    durationS,
+			createdAt:    startTime,
+			cancel:       cancel,
+			done:         make(chan struct{}),
+			state:        state,
+		}
+		registerAllocation(alloc)
+
+		logger.InfoFields(r.Context(), "Mock-memory job created",
+			field.String("request_id", requestID), field.Int("memory_mb", memoryMB), field.Int("duration_seconds", durationS))
+
+		go func() {
+			defer cancel(nil)
+			runJob(ctx, alloc, budget, reserved, logger)
+		}()
+
+		status, _ := lookupJob(requestID)
+		w.Header().Set("Location", "/api/mock-memory/jobs/"+requestID)
+		restapiutils.WriteJSONResponse(w, http.StatusAccepted, status)
+	}
+}
+
+// jobsCSV renders jobs as CSV records with a header
+func jobsCSV(jobs []JobStatus) [][]string {
+	records := [][]string{{"id", "async", "state", "memory_mb", "duration_seconds", "created_at",
+		"held_seconds", "progress_percent", "finished_at", "error"}}
+	for _, job := range jobs {
+		records = append(records, []string{
+			job.ID,
+			strconv.FormatBool(job.Async),
+			job.State,
+			strconv.Itoa(job.MemoryMB),
+			strconv.Itoa(job.DurationS),
+			job.CreatedAt,
+			strconv.FormatFloat(job.HeldSeconds, 'f', 1, 64),
+			strconv.FormatFloat(job.ProgressPercent, 'f', 1, 64),
+			job.FinishedAt,
+			job.Error,
+		})
+	}
+	return records
+}
+
+// HandleListMockMemoryJobs
+// GET /api/mock-memory/jobs
+// Lists all active mock-memory allocations, async jobs and synchronous requests, oldest first. Sent
+// as CSV when the Accept header prefers text/csv.
+func HandleListMockMemoryJobs() http.HandlerFunc {
+	type Response struct {
+		Jobs []JobStatus `json:"jobs"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+
+		allocationsMutex.Lock()
+		allocations := slices.SortedFunc(maps.Values(activeAllocations), func(a, b *allocation) int {
+			return compareRequests(a.createdAt, a.id, b.createdAt, b.id)
+		})
+		jobs := make([]JobStatus, len(allocations))
+		for i, a := range allocations {
+			jobs[i] = a.status(now)
+		}
+		allocationsMutex.Unlock()
+
+		restapiutils.WriteResponse(w, r, http.StatusOK, Response{Jobs: jobs}, restapiutils.Representations{
+			CSV: func() [][]string { return jobsCSV(jobs) },
+		})
+	}
+}
+
+// HandleGetMockMemoryJob
+// GET /api/mock-memory/jobs/{id}
+// Reports the state and progress of an allocation. Finished async jobs are kept for 10 minutes.
+func HandleGetMockMemoryJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, ok := lookupJob(r.PathValue("id"))
+		if !ok {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, status)
+	}
+}
+
+// HandleDeleteMockMemoryJob
+// DELETE /api/mock-memory/jobs/{id}
+// Releases an active allocation before its duration has passed and returns its final status.
+// Synchronous requests released this way respond with 409.
+func HandleDeleteMockMemoryJob(logger logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		allocationsMutex.Lock()
+		alloc, active := activeAllocations[id]
+		_, finished := finishedJobs[id]
+		allocationsMutex.Unlock()
+
+		if !active {
+			if finished {
+				http.Error(w, "Job already finished", http.StatusConflict)
+				return
+			}
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		logger.InfoWithCtx(r.Context(), "Releasing mock-memory allocation", "request_id", id)
+		alloc.cancel(errReleasedByRequest)
+
+		select {
+		case <-alloc.done:
+		case <-time.After(jobReleaseWait):
+		case <-r.Context().Done():
+			return
+		}
+
+		allocationsMutex.Lock()
+		status := alloc.status(time.Now())
+		allocationsMutex.Unlock()
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, status)
+	}
+}
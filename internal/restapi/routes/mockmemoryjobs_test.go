@@ -0,0 +1,127 @@
+package routes_test
+
+import (
+	"net/http"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+)
+
+// jobsMux serves the jobs API like the App does
+func jobsMux(maxJobs int) *http.ServeMux {
+	log := testlogger.New()
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/mock-memory/jobs", routes.HandleCreateMockMemoryJob(log, nil, maxJobs))
+	mux.Handle("GET /api/mock-memory/jobs", routes.HandleListMockMemoryJobs())
+	mux.Handle("GET /api/mock-memory/jobs/{id}", routes.HandleGetMockMemoryJob())
+	mux.Handle("DELETE /api/mock-memory/jobs/{id}", routes.HandleDeleteMockMemoryJob(log))
+	return mux
+}
+
+// createJob starts a job holding 1 MB for 60 seconds and returns its ID. The job is released
+// when the test ends.
+func createJob(t *testing.T, mux *http.ServeMux) string {
+	t.Helper()
+
+	w := serve(mux, newRequest(http.MethodPost, "/api/mock-memory/jobs?memory_mb=1&duration_seconds=60"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("create: status = %d, want %d, body %q", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	job := decodeJSON[routes.JobStatus](t, w)
+	if got := w.Header().Get("Location"); got != "/api/mock-memory/jobs/"+job.ID {
+		t.Errorf("Location = %q", got)
+	}
+	t.Cleanup(func() { serve(mux, newRequest(http.MethodDelete, "/api/mock-memory/jobs/"+job.ID)) })
+
+	return job.ID
+}
+
+func TestMockMemoryJobLifecycle(t *testing.T) {
+	mux := jobsMux(0)
+	id := createJob(t, mux)
+
+	w := serve(mux, newRequest(http.MethodGet, "/api/mock-memory/jobs/"+id))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	job := decodeJSON[routes.JobStatus](t, w)
+	if !job.Async || job.MemoryMB != 1 || job.DurationS != 60 || job.FinishedAt != "" {
+		t.Errorf("get: unexpected job %+v", job)
+	}
+
+	w = serve(mux, newRequest(http.MethodDelete, "/api/mock-memory/jobs/"+id))
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if job := decodeJSON[routes.JobStatus](t, w); job.State != routes.JobStateCancelled || job.FinishedAt == "" {
+		t.Errorf("delete: job = %+v, want it cancelled", job)
+	}
+
+	// Finished jobs can still be looked up, but not released again
+	w = serve(mux, newRequest(http.MethodGet, "/api/mock-memory/jobs/"+id))
+	if job := decodeJSON[routes.JobStatus](t, w); job.State != routes.JobStateCancelled {
+		t.Errorf("get after delete: state = %q, want %q", job.State, routes.JobStateCancelled)
+	}
+	if w := serve(mux, newRequest(http.MethodDelete, "/api/mock-memory/jobs/"+id)); w.Code != http.StatusConflict {
+		t.Errorf("second delete: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestMockMemoryJobNotFound(t *testing.T) {
+	mux := jobsMux(0)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := serve(mux, newRequest(method, "/api/mock-memory/jobs/unknown")); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", method, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestListMockMemoryJobsOrder(t *testing.T) {
+	mux := jobsMux(0)
+
+	// IDs 9, 10 and 11 sort wrongly as strings
+	routes.SetLastRequestID(8)
+	var want []string
+	for range 3 {
+		want = append(want, createJob(t, mux))
+	}
+
+	w := serve(mux, newRequest(http.MethodGet, "/api/mock-memory/jobs"))
+	resp := decodeJSON[struct{ Jobs []routes.JobStatus }](t, w)
+	var got []string
+	for _, job := range resp.Jobs {
+		got = append(got, job.ID)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("listed jobs %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("listed jobs %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCreateMockMemoryJobLimit(t *testing.T) {
+	mux := jobsMux(2)
+	first := createJob(t, mux)
+	createJob(t, mux)
+
+	w := serve(mux, newRequest(http.MethodPost, "/api/mock-memory/jobs?memory_mb=1&duration_seconds=60"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("job over the limit: status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if resp := decodeJSON[map[string]any](t, w); resp["details"] != routes.ErrTooManyJobs.Error() {
+		t.Errorf("details = %v, want %q", resp["details"], routes.ErrTooManyJobs)
+	}
+
+	// Finished jobs give their slot back
+	serve(mux, newRequest(http.MethodDelete, "/api/mock-memory/jobs/"+first))
+	w = serve(mux, newRequest(http.MethodPost, "/api/mock-memory/jobs?memory_mb=1&duration_seconds=60"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("job after a release: status = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	id := decodeJSON[routes.JobStatus](t, w).ID
+	t.Cleanup(func() { serve(mux, newRequest(http.MethodDelete, "/api/mock-memory/jobs/"+id)) })
+}
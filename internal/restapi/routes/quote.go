@@ -1,16 +1,69 @@
 package routes
 
 import (
+	"errors"
+	"html"
 	"net/http"
 	"quote-service/internal/repository"
 	restapiutils "quote-service/internal/restapi/utils"
 	"quote-service/pkg/authorclient"
 	"quote-service/pkg/logger"
 	"strconv"
+	"sync"
+	"time"
 )
 
+// etagDates remembers when the current ETag of each quote was first served, which is used as its
+// Last-Modified date since the author-service doesn't say when authors change. Dates have whole
+// seconds like HTTP dates, and a new tag always gets a later date than the previous one, so
+// If-Modified-Since with an old date never matches after a change within the same second. Dates
+// are kept in memory, so they move forward on restarts and differ between replicas.
+type etagDates struct {
+	mu    sync.Mutex
+	dates map[int]etagDate
+}
+
+type etagDate struct {
+	etag string
+	date time.Time
+}
+
+// lastModified returns the date the quote with the given ID was first served with etag
+func (d *etagDates) lastModified(id int, etag string, now time.Time) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	previous, ok := d.dates[id]
+	if ok && previous.etag == etag {
+		return previous.date
+	}
+
+	date := now.UTC().Truncate(time.Second)
+	if ok && !date.After(previous.date) {
+		date = previous.date.Add(time.Second)
+	}
+	d.dates[id] = etagDate{etag: etag, date: date}
+	return date
+}
+
+// quoteRepresentations renders a quote as plain text and as an HTML snippet for embedding
+func quoteRepresentations(message, author string) restapiutils.Representations {
+	return restapiutils.Representations{
+		Text: func() string {
+			return "\"" + message + "\" — " + author
+		},
+		HTML: func() string {
+			return `<blockquote class="quote"><p>` + html.EscapeString(message) + `</p><footer>` +
+				html.EscapeString(author) + `</footer></blockquote>`
+		},
+	}
+}
+
 // HandleGetQuoteByID
 // /api/quote/{id}
+// Responds with JSON, plain text or an HTML snippet depending on the Accept header. Responses carry
+// a strong ETag of the quote and author and the date that tag was first served as Last-Modified,
+// and conditional requests are answered with 304.
 func HandleGetQuoteByID(logger logger.Logger, repo repository.Repository, authorClient *authorclient.Client) http.HandlerFunc {
 	type AuthorInfo struct {
 		ID   int    `json:"id"`
@@ -23,6 +76,8 @@ func HandleGetQuoteByID(logger logger.Logger, repo repository.Repository, author
 		Author  AuthorInfo `json:"author"`
 	}
 
+	dates := &etagDates{dates: make(map[int]etagDate)}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.PathValue("id")
 		id, err := strconv.Atoi(idStr)
@@ -31,9 +86,9 @@ func HandleGetQuoteByID(logger logger.Logger, repo repository.Repository, author
 			return
 		}
 
-		quote, err := repo.GetQuoteByID(id)
+		quote, err := repo.GetQuoteByID(r.Context(), id)
 		if err != nil {
-			if err == repository.ErrNotFound {
+			if errors.Is(err, repository.ErrNotFound) {
 				http.Error(w, "Quote not found", http.StatusNotFound)
 				return
 			}
@@ -42,7 +97,7 @@ func HandleGetQuoteByID(logger logger.Logger, repo repository.Repository, author
 			return
 		}
 
-		authors, err := authorClient.GetAuthorsByIDs([]int{quote.AuthorID})
+		authors, err := authorClient.GetAuthorsByIDs(r.Context(), []int{quote.AuthorID})
 		if err != nil {
 			logger.ErrorWithCtx(r.Context(), "Failed to get author", "error", err.Error())
 			http.Error(w, "Failed to get author information", http.StatusInternalServerError)
@@ -64,12 +119,17 @@ func HandleGetQuoteByID(logger logger.Logger, repo repository.Repository, author
 			},
 		}
 
-		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
+		// The tag changes with the quote or its author, and with it the Last-Modified date
+		etag := restapiutils.ETag(strconv.Itoa(quote.ID), quote.Message, strconv.Itoa(authors[0].ID), authors[0].Name)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", dates.lastModified(quote.ID, etag, time.Now()).Format(http.TimeFormat))
+		restapiutils.WriteResponse(w, r, http.StatusOK, resp, quoteRepresentations(quote.Message, authors[0].Name))
 	}
 }
 
 // HandleGetRandomQuote
 // /api/quote/random
+// Responds with JSON, plain text or an HTML snippet depending on the Accept header.
 func HandleGetRandomQuote(logger logger.Logger, repo repository.Repository, authorClient *authorclient.Client) http.HandlerFunc {
 	type AuthorInfo struct {
 		ID   int    `json:"id"`
@@ -83,9 +143,9 @@ func HandleGetRandomQuote(logger logger.Logger, repo repository.Repository, auth
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		quote, err := repo.GetRandomQuote()
+		quote, err := repo.GetRandomQuote(r.Context())
 		if err != nil {
-			if err == repository.ErrNotFound {
+			if errors.Is(err, repository.ErrNotFound) {
 				http.Error(w, "No quotes found", http.StatusNotFound)
 				return
 			}
@@ -94,7 +154,7 @@ func HandleGetRandomQuote(logger logger.Logger, repo repository.Repository, auth
 			return
 		}
 
-		authors, err := authorClient.GetAuthorsByIDs([]int{quote.AuthorID})
+		authors, err := authorClient.GetAuthorsByIDs(r.Context(), []int{quote.AuthorID})
 		if err != nil {
 			logger.ErrorWithCtx(r.Context(), "Failed to get author", "error", err.Error())
 			http.Error(w, "Failed to get author information", http.StatusInternalServerError)
@@ -116,6 +176,6 @@ func HandleGetRandomQuote(logger logger.Logger, repo repository.Repository, auth
 			},
 		}
 
-		restapiutils.WriteJSONResponse(w, http.StatusOK, resp)
+		restapiutils.WriteResponse(w, r, http.StatusOK, resp, quoteRepresentations(quote.Message, authors[0].Name))
 	}
 }
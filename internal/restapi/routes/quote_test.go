@@ -0,0 +1,261 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	hardcodedrepository "quote-service/internal/repository/hardcoded_adapter"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger/testlogger"
+	"strings"
+	"testing"
+	"time"
+)
+
+type quoteResponse struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+	Author  struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"author"`
+}
+
+func TestHandleGetQuoteByID(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		authors       map[int]string
+		authorStatus  int
+		wantStatus    int
+		wantAuthor    string
+		wantErrorLog  string
+		wantErrorAttr map[string]string
+	}{
+		{
+			name:       "found",
+			id:         "1",
+			authors:    map[int]string{1: "Bernard Baruch"},
+			wantStatus: http.StatusOK,
+			wantAuthor: "Bernard Baruch",
+		},
+		{
+			name:       "invalid id",
+			id:         "abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown quote",
+			id:         "999",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:          "author-service failure",
+			id:            "1",
+			authorStatus:  http.StatusBadGateway,
+			wantStatus:    http.StatusInternalServerError,
+			wantErrorLog:  "Failed to get author",
+			wantErrorAttr: map[string]string{"error": "unexpected status code: 502"},
+		},
+		{
+			name:          "unknown author",
+			id:            "1",
+			authors:       map[int]string{},
+			wantStatus:    http.StatusNotFound,
+			wantErrorLog:  "Author not found",
+			wantErrorAttr: map[string]string{"authorID": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorService := newFakeAuthorService(t)
+			authorService.authors = tt.authors
+			authorService.status = tt.authorStatus
+			log := testlogger.New()
+
+			handler := routes.HandleGetQuoteByID(log, hardcodedrepository.NewHardcodedRepository(), authorService.client())
+			r := newRequest(http.MethodGet, "/api/quote/"+tt.id)
+			r.SetPathValue("id", tt.id)
+			w := serve(handler, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				resp := decodeJSON[quoteResponse](t, w)
+				if resp.ID != 1 || resp.Message == "" || resp.Author.ID != 1 || resp.Author.Name != tt.wantAuthor {
+					t.Errorf("unexpected response %+v", resp)
+				}
+				if got := authorService.lastRequestID(); got != testTraceID {
+					t.Errorf("author-service got X-Request-ID %q, want %q", got, testTraceID)
+				}
+			}
+
+			if tt.wantErrorLog == "" {
+				if errors := log.ByLevel(testlogger.LevelError); len(errors) != 0 {
+					t.Errorf("unexpected error logs:\n%s", log)
+				}
+				return
+			}
+
+			entry, ok := log.Find(testlogger.LevelError, tt.wantErrorLog)
+			if !ok {
+				t.Fatalf("no error log %q in:\n%s", tt.wantErrorLog, log)
+			}
+			if entry.TraceID != testTraceID {
+				t.Errorf("trace ID = %q, want %q", entry.TraceID, testTraceID)
+			}
+			for key, want := range tt.wantErrorAttr {
+				if got := entry.String(key); got != want {
+					t.Errorf("attribute %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleGetRandomQuote(t *testing.T) {
+	authorService := newFakeAuthorService(t)
+	for id := 1; id <= 20; id++ {
+		authorService.authors[id] = "Author"
+	}
+	log := testlogger.New()
+	handler := routes.HandleGetRandomQuote(log, hardcodedrepository.NewHardcodedRepository(), authorService.client())
+
+	w := serve(handler, newRequest(http.MethodGet, "/api/quote/random"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusOK, w.Body.String())
+	}
+	resp := decodeJSON[quoteResponse](t, w)
+	if resp.ID < 1 || resp.ID > 20 || resp.Author.ID != resp.ID {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if log.Len() != 0 {
+		t.Errorf("unexpected logs:\n%s", log)
+	}
+
+	authorService.status = http.StatusServiceUnavailable
+	w = serve(handler, newRequest(http.MethodGet, "/api/quote/random"))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if !log.Has(testlogger.LevelError, "Failed to get author") {
+		t.Errorf("author-service failure not logged:\n%s", log)
+	}
+}
+
+func TestHandleGetQuoteByIDRepresentations(t *testing.T) {
+	tests := []struct {
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"", http.StatusOK, "application/json", `"author":{"id":3,"name":"Sam \u003cSmith\u003e"}`},
+		{"application/json", http.StatusOK, "application/json", `"id":3`},
+		{"text/plain", http.StatusOK, "text/plain; charset=utf-8", `"Wrinkles should merely indicate where smiles have been." — Sam <Smith>`},
+		{"text/html, application/json;q=0.9", http.StatusOK, "text/html; charset=utf-8", `<footer>Sam &lt;Smith&gt;</footer>`},
+		{"text/*;q=0.5, application/json;q=0.1", http.StatusOK, "text/plain; charset=utf-8", "Wrinkles"},
+		{"text/csv", http.StatusNotAcceptable, "text/plain; charset=utf-8", "text/html"},
+	}
+
+	authorService := newFakeAuthorService(t)
+	authorService.authors = map[int]string{3: "Sam <Smith>"}
+	handler := routes.HandleGetQuoteByID(testlogger.New(), hardcodedrepository.NewHardcodedRepository(), authorService.client())
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := newRequest(http.MethodGet, "/api/quote/3")
+			r.SetPathValue("id", "3")
+			r.Header.Set("Accept", tt.accept)
+			w := serve(handler, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %q doesn't contain %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandleGetQuoteByIDConditional(t *testing.T) {
+	authorService := newFakeAuthorService(t)
+	authorService.authors = map[int]string{3: "Sam Smith"}
+	handler := routes.HandleGetQuoteByID(testlogger.New(), hardcodedrepository.NewHardcodedRepository(), authorService.client())
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		r := newRequest(http.MethodGet, "/api/quote/3")
+		r.SetPathValue("id", "3")
+		for key, values := range header {
+			r.Header[key] = values
+		}
+		return serve(handler, r)
+	}
+
+	first := get(nil)
+	etag := first.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("unexpected ETag %q", etag)
+	}
+	lastModified := first.Header().Get("Last-Modified")
+	firstDate, err := http.ParseTime(lastModified)
+	if err != nil {
+		t.Fatalf("unexpected Last-Modified %q: %v", lastModified, err)
+	}
+	earlier := firstDate.Add(-time.Hour).Format(http.TimeFormat)
+	if again := get(nil).Header(); again.Get("ETag") != etag || again.Get("Last-Modified") != lastModified {
+		t.Errorf("validators changed from %s, %s to %s, %s", etag, lastModified, again.Get("ETag"), again.Get("Last-Modified"))
+	}
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{"matching etag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"weak etag", http.Header{"If-None-Match": {`"other", W/` + etag}}, http.StatusNotModified},
+		{"other etag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"same date", http.Header{"If-Modified-Since": {lastModified}}, http.StatusNotModified},
+		{"earlier date", http.Header{"If-Modified-Since": {earlier}}, http.StatusOK},
+		{"etag with date", http.Header{"If-None-Match": {etag}, "If-Modified-Since": {earlier}}, http.StatusNotModified},
+		{"other etag with date", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {lastModified}}, http.StatusOK},
+		{"other representation", http.Header{"If-None-Match": {etag}, "Accept": {"text/html"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with body %q", w.Body.String())
+			}
+		})
+	}
+
+	// The change most likely happens within the same second, the new date is later nonetheless
+	authorService.authors[3] = "Samuel Smith"
+	changed := get(nil).Header()
+	if changed.Get("ETag") == etag {
+		t.Errorf("ETag didn't change with the author")
+	}
+	if changedDate, err := http.ParseTime(changed.Get("Last-Modified")); err != nil || !changedDate.After(firstDate) {
+		t.Errorf("Last-Modified after an author change = %q, want a date after %s", changed.Get("Last-Modified"), lastModified)
+	}
+	if w := get(http.Header{"If-None-Match": {etag}}); w.Code != http.StatusOK {
+		t.Errorf("stale ETag got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := get(http.Header{"If-Modified-Since": {lastModified}}); w.Code != http.StatusOK {
+		t.Errorf("revalidating by date after an author change got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := get(http.Header{"If-Modified-Since": {changed.Get("Last-Modified")}}); w.Code != http.StatusNotModified {
+		t.Errorf("revalidating with the new date got status %d, want %d", w.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,537 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	restapiutils "quote-service/internal/restapi/utils"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ScenarioStateRunning is the state of a scenario executing its phases. Finished scenarios
+	// end up completed, cancelled or failed like mock-memory jobs.
+	ScenarioStateRunning = "running"
+
+	// scenarioStep is how often a running scenario adjusts its memory and CPU load
+	scenarioStep = time.Second
+	// maxScenarioPhases bounds the length of a profile
+	maxScenarioPhases = 20
+	// maxScenarioBodyBytes bounds the size of a profile
+	maxScenarioBodyBytes = 1 << 20
+)
+
+var errScenarioRunning = errors.New("a scenario is already running")
+
+// ScenarioPhase is one step of a load profile. During the first RampS seconds the memory and CPU
+// load move linearly from the previous phase's levels to the ones of this phase, then they are
+// held for the rest of DurationS.
+type ScenarioPhase struct {
+	Name                  string `json:"name,omitempty" yaml:"name"`
+	MemoryMB              int    `json:"memory_mb" yaml:"memory_mb"`
+	CPUCores              int    `json:"cpu_cores" yaml:"cpu_cores"`
+	CPUUtilizationPercent int    `json:"cpu_utilization_percent" yaml:"cpu_utilization_percent"`
+	DurationS             int    `json:"duration_seconds" yaml:"duration_seconds"`
+	RampS                 int    `json:"ramp_seconds" yaml:"ramp_seconds"`
+}
+
+// ScenarioProfile is a named list of phases executed in order
+type ScenarioProfile struct {
+	Name   string          `json:"name,omitempty" yaml:"name"`
+	Phases []ScenarioPhase `json:"phases" yaml:"phases"`
+}
+
+// validate checks the bounds of every phase, using the same limits as the mock endpoints, and
+// fills in the default CPU utilization
+func (p *ScenarioProfile) validate() error {
+	if len(p.Phases) == 0 || len(p.Phases) > maxScenarioPhases {
+		return fmt.Errorf("a profile must have between 1 and %d phases", maxScenarioPhases)
+	}
+
+	maxCores := runtime.NumCPU()
+	for i := range p.Phases {
+		phase := &p.Phases[i]
+		switch {
+		case phase.MemoryMB < 0 || phase.MemoryMB > 1000:
+			return fmt.Errorf("phase %d: memory_mb must be between 0 and 1000", i+1)
+		case phase.CPUCores < 0 || phase.CPUCores > maxCores:
+			return fmt.Errorf("phase %d: cpu_cores must be between 0 and %d", i+1, maxCores)
+		case phase.CPUUtilizationPercent < 0 || phase.CPUUtilizationPercent > 100:
+			return fmt.Errorf("phase %d: cpu_utilization_percent must be between 0 and 100", i+1)
+		case phase.DurationS < 1 || phase.DurationS > 300:
+			return fmt.Errorf("phase %d: duration_seconds must be between 1 and 300", i+1)
+		case phase.RampS < 0 || phase.RampS > phase.DurationS:
+			return fmt.Errorf("phase %d: ramp_seconds must be between 0 and duration_seconds", i+1)
+		}
+
+		if phase.CPUCores > 0 && phase.CPUUtilizationPercent == 0 {
+			phase.CPUUtilizationPercent = 100
+		}
+	}
+	return nil
+}
+
+// levels returns the memory (in MB) and CPU load (in percent of a core) of the given step of the
+// phase, moving linearly from the previous phase's levels during the ramp
+func (p ScenarioPhase) levels(previousMemoryMB int, previousLoad float64, step int) (int, float64) {
+	fraction := 1.0
+	if step < p.RampS {
+		fraction = float64(step+1) / float64(p.RampS)
+	}
+
+	targetLoad := float64(p.CPUCores * p.CPUUtilizationPercent)
+	memoryMB := previousMemoryMB + int(math.Round(float64(p.MemoryMB-previousMemoryMB)*fraction))
+	return memoryMB, previousLoad + (targetLoad-previousLoad)*fraction
+}
+
+// ScenarioStatus describes a scenario and the load it currently generates
+type ScenarioStatus struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name,omitempty"`
+	State           string          `json:"state"`
+	Phase           int             `json:"phase"`
+	PhaseName       string          `json:"phase_name,omitempty"`
+	MemoryMB        int             `json:"memory_mb"`
+	CPULoadPercent  float64         `json:"cpu_load_percent"`
+	ElapsedSeconds  float64         `json:"elapsed_seconds"`
+	TotalSeconds    int             `json:"total_seconds"`
+	ProgressPercent float64         `json:"progress_percent"`
+	CreatedAt       string          `json:"created_at"`
+	FinishedAt      string          `json:"finished_at,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	Phases          []ScenarioPhase `json:"phases"`
+}
+
+// scenario is a load profile being executed. Its memory is held by alloc, which is registered in
+// activeAllocations so it shows up in the jobs API and metrics, and is released during shutdown.
+type scenario struct {
+	id        string
+	profile   ScenarioProfile
+	createdAt time.Time
+	alloc     *allocation
+
+	// Fields below are guarded by scenariosMutex
+	state          string
+	phase          int
+	completedSteps int
+	cpuLoadPercent float64
+	finishedAt     time.Time
+	err            string
+}
+
+// Global variables to track scenarios. Finished scenarios are kept for jobRetention.
+var (
+	scenarios      = make(map[string]*scenario)
+	scenariosMutex sync.Mutex
+)
+
+// status returns the ScenarioStatus of s. The caller must hold scenariosMutex.
+func (s *scenario) status(now time.Time) ScenarioStatus {
+	status := ScenarioStatus{
+		ID:             s.id,
+		Name:           s.profile.Name,
+		State:          s.state,
+		Phase:          s.phase,
+		CPULoadPercent: math.Round(s.cpuLoadPercent*10) / 10,
+		CreatedAt:      s.createdAt.Format(time.RFC3339),
+		Error:          s.err,
+		Phases:         s.profile.Phases,
+	}
+	if s.phase > 0 {
+		status.PhaseName = s.profile.Phases[s.phase-1].Name
+	}
+	for _, phase := range s.profile.Phases {
+		status.TotalSeconds += phase.DurationS
+	}
+
+	if !s.finishedAt.IsZero() {
+		status.FinishedAt = s.finishedAt.Format(time.RFC3339)
+		now = s.finishedAt
+	}
+	status.ElapsedSeconds = now.Sub(s.createdAt).Seconds()
+	// Based on the completed steps, as waiting for the memory budget can make a scenario run
+	// longer than its phases
+	status.ProgressPercent = float64(s.completedSteps) / float64(status.TotalSeconds) * 100
+
+	allocationsMutex.Lock()
+	status.MemoryMB = s.alloc.memoryMB
+	allocationsMutex.Unlock()
+
+	return status
+}
+
+// startScenario registers a new scenario unless one is already running
+func startScenario(s *scenario) error {
+	scenariosMutex.Lock()
+	defer scenariosMutex.Unlock()
+
+	now := time.Now()
+	for id, other := range scenarios {
+		if other.state == ScenarioStateRunning {
+			return errScenarioRunning
+		}
+		if now.Sub(other.finishedAt) > jobRetention {
+			delete(scenarios, id)
+		}
+	}
+
+	scenarios[s.id] = s
+	registerAllocation(s.alloc)
+	setAllocationState(s.alloc, JobStateHolding)
+	return nil
+}
+
+// lookupScenario returns the status of a scenario
+func lookupScenario(id string) (ScenarioStatus, bool) {
+	scenariosMutex.Lock()
+	defer scenariosMutex.Unlock()
+
+	s, ok := scenarios[id]
+	if !ok {
+		return ScenarioStatus{}, false
+	}
+	return s.status(time.Now()), true
+}
+
+// setMemory grows or shrinks the memory held by s to memoryMB, reserving the difference in the
+// memory budget first
+func (s *scenario) setMemory(ctx context.Context, memoryMB int, budget *MemoryBudget) error {
+	allocationsMutex.Lock()
+	delta := memoryMB - s.alloc.memoryMB
+	allocationsMutex.Unlock()
+
+	switch {
+	case delta > 0:
+		if err := budget.Acquire(ctx, delta); err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return err
+		}
+		blocks := allocateBlocks(delta)
+
+		allocationsMutex.Lock()
+		s.alloc.data = append(s.alloc.data, blocks...)
+		s.alloc.memoryMB = memoryMB
+		allocationsMutex.Unlock()
+	case delta < 0:
+		allocationsMutex.Lock()
+		// Clear the dropped blocks so they can be collected
+		clear(s.alloc.data[memoryMB:])
+		s.alloc.data = s.alloc.data[:memoryMB]
+		s.alloc.memoryMB = memoryMB
+		allocationsMutex.Unlock()
+
+		budget.Release(-delta)
+	}
+	return nil
+}
+
+// generateCPULoad burns loadPercent percent of a core (150 is one and a half cores) for
+// duration, or waits for duration if there is no load
+func generateCPULoad(ctx context.Context, loadPercent float64, duration time.Duration) {
+	if loadPercent <= 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(duration):
+		}
+		return
+	}
+
+	cores := min(int(math.Ceil(loadPercent/100)), runtime.NumCPU())
+	utilizationPercent := min(100, max(1, int(math.Round(loadPercent/float64(cores)))))
+	burnCPU(ctx, cores, utilizationPercent, duration)
+}
+
+// runScenario executes the phases of s in steps of scenarioStep until they are done or ctx is
+// cancelled
+func runScenario(ctx context.Context, s *scenario, budget *MemoryBudget, logger logger.Logger) {
+	err := executePhases(ctx, s, budget, logger)
+
+	// Release the remaining memory
+	allocationsMutex.Lock()
+	heldMB := s.alloc.memoryMB
+	s.alloc.memoryMB = 0
+	allocationsMutex.Unlock()
+	budget.Release(heldMB)
+
+	state := JobStateCompleted
+	if err != nil {
+		state = jobEndState(err)
+		logger.WarnFields(ctx, "Scenario stopped early", field.String("scenario_id", s.id), field.String("reason", err.Error()))
+	} else {
+		logger.InfoFields(ctx, "Scenario completed", field.String("scenario_id", s.id))
+	}
+
+	scenariosMutex.Lock()
+	s.state = state
+	s.cpuLoadPercent = 0
+	s.finishedAt = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	scenariosMutex.Unlock()
+
+	// Closes alloc.done, so the scenario must be finished first
+	finishJob(s.alloc, state, err)
+}
+
+// executePhases applies the memory and CPU levels of every phase, ramping from the levels of the
+// previous phase
+func executePhases(ctx context.Context, s *scenario, budget *MemoryBudget, logger logger.Logger) error {
+	previousMemoryMB, previousLoad := 0, 0.0
+
+	for i, phase := range s.profile.Phases {
+		scenariosMutex.Lock()
+		s.phase = i + 1
+		scenariosMutex.Unlock()
+
+		logger.InfoFields(ctx, "Scenario phase started",
+			field.String("scenario_id", s.id), field.Int("phase", i+1), field.String("phase_name", phase.Name),
+			field.Int("memory_mb", phase.MemoryMB), field.Int("cpu_cores", phase.CPUCores),
+			field.Int("cpu_utilization_percent", phase.CPUUtilizationPercent),
+			field.Int("duration_seconds", phase.DurationS), field.Int("ramp_seconds", phase.RampS))
+
+		for step := range phase.DurationS {
+			memoryMB, load := phase.levels(previousMemoryMB, previousLoad, step)
+			if err := s.setMemory(ctx, memoryMB, budget); err != nil {
+				return err
+			}
+
+			scenariosMutex.Lock()
+			s.cpuLoadPercent = load
+			scenariosMutex.Unlock()
+
+			generateCPULoad(ctx, load, scenarioStep)
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+
+			scenariosMutex.Lock()
+			s.completedSteps++
+			scenariosMutex.Unlock()
+		}
+
+		previousMemoryMB, previousLoad = phase.MemoryMB, float64(phase.CPUCores*phase.CPUUtilizationPercent)
+	}
+	return nil
+}
+
+// decodeScenarioProfile reads a profile from the request body as YAML if the content type says
+// so, and as JSON otherwise
+func decodeScenarioProfile(r *http.Request, w http.ResponseWriter) (ScenarioProfile, error) {
+	var profile ScenarioProfile
+	body := http.MaxBytesReader(w, r.Body, maxScenarioBodyBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasSuffix(mediaType, "yaml") {
+		decoder := yaml.NewDecoder(body)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&profile); err != nil && !errors.Is(err, io.EOF) {
+			return ScenarioProfile{}, err
+		}
+		return profile, nil
+	}
+
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		return ScenarioProfile{}, err
+	}
+	return profile, nil
+}
+
+// HandleCreateScenario
+// POST /api/admin/scenarios
+// Starts a load profile in the background and returns its status immediately. The body is a
+// JSON (or YAML, with a YAML content type) profile, e.g.
+// {"name": "scale-up", "phases": [{"memory_mb": 500, "cpu_cores": 2, "cpu_utilization_percent": 80,
+// "duration_seconds": 120, "ramp_seconds": 30}]}.
+// Only one scenario runs at a time; starting another responds with 409.
+func HandleCreateScenario(logger logger.Logger, budget *MemoryBudget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		scenarioID := newRequestID()
+
+		profile, err := decodeScenarioProfile(r, w)
+		if err == nil {
+			err = profile.validate()
+		}
+		if err != nil {
+			logger.WarnFields(r.Context(), "Invalid scenario profile", field.String("scenario_id", scenarioID), field.Err(err))
+			errorResp := requestError{
+				Error:   "Invalid scenario profile",
+				Code:    400,
+				Details: err.Error(),
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusBadRequest, errorResp)
+			return
+		}
+
+		totalS := 0
+		for _, phase := range profile.Phases {
+			totalS += phase.DurationS
+		}
+
+		// The scenario outlives the request, it keeps the request's values (like the trace ID) but
+		// not its cancellation
+		ctx, cancel := context.WithCancelCause(context.WithoutCancel(r.Context()))
+
+		s := &scenario{
+			id:        scenarioID,
+			profile:   profile,
+			createdAt: startTime,
+			state:     ScenarioStateRunning,
+			alloc: &allocation{
+				id:        scenarioID,
+				async:     true,
+				durationS: totalS,
+				createdAt: startTime,
+				cancel:    cancel,
+				done:      make(chan struct{}),
+				state:     JobStateAllocating,
+			},
+		}
+		if err := startScenario(s); err != nil {
+			cancel(nil)
+			errorResp := requestError{
+				Error: "Scenario already running",
+				Code:  409,
+			}
+			restapiutils.WriteJSONResponse(w, http.StatusConflict, errorResp)
+			return
+		}
+
+		logger.InfoFields(r.Context(), "Scenario started",
+			field.String("scenario_id", scenarioID), field.String("name", profile.Name),
+			field.Int("phases", len(profile.Phases)), field.Int("total_seconds", totalS))
+
+		go func() {
+			defer cancel(nil)
+			runScenario(ctx, s, budget, logger)
+		}()
+
+		status, _ := lookupScenario(scenarioID)
+		w.Header().Set("Location", "/api/admin/scenarios/"+scenarioID)
+		restapiutils.WriteJSONResponse(w, http.StatusAccepted, status)
+	}
+}
+
+// scenariosCSV renders scenario statuses as CSV records with a header, without their phases
+func scenariosCSV(statuses []ScenarioStatus) [][]string {
+	records := [][]string{{"id", "name", "state", "phase", "phase_name", "memory_mb", "cpu_load_percent",
+		"elapsed_seconds", "total_seconds", "progress_percent", "created_at", "finished_at", "error"}}
+	for _, s := range statuses {
+		records = append(records, []string{
+			s.ID,
+			s.Name,
+			s.State,
+			strconv.Itoa(s.Phase),
+			s.PhaseName,
+			strconv.Itoa(s.MemoryMB),
+			strconv.FormatFloat(s.CPULoadPercent, 'f', 1, 64),
+			strconv.FormatFloat(s.ElapsedSeconds, 'f', 1, 64),
+			strconv.Itoa(s.TotalSeconds),
+			strconv.FormatFloat(s.ProgressPercent, 'f', 1, 64),
+			s.CreatedAt,
+			s.FinishedAt,
+			s.Error,
+		})
+	}
+	return records
+}
+
+// HandleListScenarios
+// GET /api/admin/scenarios
+// Lists the running scenario and the ones that finished in the last 10 minutes, oldest first. Sent
+// as CSV when the Accept header prefers text/csv.
+func HandleListScenarios() http.HandlerFunc {
+	type Response struct {
+		Scenarios []ScenarioStatus `json:"scenarios"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+
+		scenariosMutex.Lock()
+		sorted := slices.SortedFunc(maps.Values(scenarios), func(a, b *scenario) int {
+			return compareRequests(a.createdAt, a.id, b.createdAt, b.id)
+		})
+		statuses := make([]ScenarioStatus, len(sorted))
+		for i, s := range sorted {
+			statuses[i] = s.status(now)
+		}
+		scenariosMutex.Unlock()
+
+		restapiutils.WriteResponse(w, r, http.StatusOK, Response{Scenarios: statuses}, restapiutils.Representations{
+			CSV: func() [][]string { return scenariosCSV(statuses) },
+		})
+	}
+}
+
+// HandleGetScenario
+// GET /api/admin/scenarios/{id}
+// Reports the phase, current load and progress of a scenario.
+func HandleGetScenario() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, ok := lookupScenario(r.PathValue("id"))
+		if !ok {
+			http.Error(w, "Scenario not found", http.StatusNotFound)
+			return
+		}
+
+		restapiutils.WriteJSONResponse(w, http.StatusOK, status)
+	}
+}
+
+// HandleDeleteScenario
+// DELETE /api/admin/scenarios/{id}
+// Aborts a running scenario, releasing its memory and stopping its CPU load, and returns its
+// final status.
+func HandleDeleteScenario(logger logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		scenariosMutex.Lock()
+		s, ok := scenarios[id]
+		running := ok && s.state == ScenarioStateRunning
+		scenariosMutex.Unlock()
+
+		if !ok {
+			http.Error(w, "Scenario not found", http.StatusNotFound)
+			return
+		}
+		if !running {
+			http.Error(w, "Scenario already finished", http.StatusConflict)
+			return
+		}
+
+		logger.InfoWithCtx(r.Context(), "Aborting scenario", "scenario_id", id)
+		s.alloc.cancel(errReleasedByRequest)
+
+		select {
+		case <-s.alloc.done:
+		case <-time.After(jobReleaseWait):
+		case <-r.Context().Done():
+			return
+		}
+
+		status, _ := lookupScenario(id)
+		restapiutils.WriteJSONResponse(w, http.StatusOK, status)
+	}
+}
@@ -0,0 +1,294 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/testlogger"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scenariosMux serves the scenarios API like the App does
+func scenariosMux(budget *routes.MemoryBudget) *http.ServeMux {
+	log := testlogger.New()
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/admin/scenarios", routes.HandleCreateScenario(log, budget))
+	mux.Handle("GET /api/admin/scenarios", routes.HandleListScenarios())
+	mux.Handle("GET /api/admin/scenarios/{id}", routes.HandleGetScenario())
+	mux.Handle("DELETE /api/admin/scenarios/{id}", routes.HandleDeleteScenario(log))
+	return mux
+}
+
+// postScenario sends a profile to the create route
+func postScenario(mux *http.ServeMux, contentType, profile string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/scenarios", strings.NewReader(profile))
+	r.Header.Set("Content-Type", contentType)
+	return serve(mux, r.WithContext(logger.WithTraceID(r.Context(), testTraceID)))
+}
+
+// createScenario starts a scenario from a JSON profile and returns its status. The scenario is
+// aborted when the test ends.
+func createScenario(t *testing.T, mux *http.ServeMux, profile string) routes.ScenarioStatus {
+	t.Helper()
+
+	w := postScenario(mux, "application/json", profile)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("create: status = %d, want %d, body %q", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	status := decodeJSON[routes.ScenarioStatus](t, w)
+	if got := w.Header().Get("Location"); got != "/api/admin/scenarios/"+status.ID {
+		t.Errorf("Location = %q", got)
+	}
+	t.Cleanup(func() { serve(mux, newRequest(http.MethodDelete, "/api/admin/scenarios/"+status.ID)) })
+
+	return status
+}
+
+// waitForScenario polls a scenario until done returns true for its status
+func waitForScenario(t *testing.T, mux *http.ServeMux, id string, done func(routes.ScenarioStatus) bool) routes.ScenarioStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := serve(mux, newRequest(http.MethodGet, "/api/admin/scenarios/"+id))
+		status := decodeJSON[routes.ScenarioStatus](t, w)
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scenario stuck at %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func finished(status routes.ScenarioStatus) bool {
+	return status.State != routes.ScenarioStateRunning
+}
+
+// waitForBudget polls the budget until usedMB are reserved
+func waitForBudget(t *testing.T, budget *routes.MemoryBudget, usedMB int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for budget.Usage().UsedMB != usedMB {
+		if time.Now().After(deadline) {
+			t.Fatalf("budget usage = %+v, want %d MB used", budget.Usage(), usedMB)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreateScenarioInvalidProfile(t *testing.T) {
+	phase := func(fields string) string { return `{"phases": [{` + fields + `}]}` }
+	tooMany := `{"phases": [` + strings.Repeat(`{"duration_seconds": 1},`, 20) + `{"duration_seconds": 1}]}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "no phases", body: `{"phases": []}`},
+		{name: "too many phases", body: tooMany},
+		{name: "negative memory", body: phase(`"memory_mb": -1, "duration_seconds": 1`)},
+		{name: "too much memory", body: phase(`"memory_mb": 1001, "duration_seconds": 1`)},
+		{name: "negative cores", body: phase(`"cpu_cores": -1, "duration_seconds": 1`)},
+		{name: "too many cores", body: phase(`"cpu_cores": ` + strconv.Itoa(runtime.NumCPU()+1) + `, "duration_seconds": 1`)},
+		{name: "utilization too high", body: phase(`"cpu_cores": 1, "cpu_utilization_percent": 101, "duration_seconds": 1`)},
+		{name: "no duration", body: phase(`"memory_mb": 1`)},
+		{name: "duration too long", body: phase(`"duration_seconds": 301`)},
+		{name: "negative ramp", body: phase(`"duration_seconds": 10, "ramp_seconds": -1`)},
+		{name: "ramp longer than the phase", body: phase(`"duration_seconds": 10, "ramp_seconds": 11`)},
+		{name: "unknown JSON field", body: phase(`"duration_seconds": 1, "memory": 1`)},
+		{name: "malformed JSON", body: `{"phases": [`},
+		{name: "unknown YAML field", contentType: "application/yaml", body: "phases:\n  - {duration_seconds: 1, memory: 1}\n"},
+		{name: "malformed YAML", contentType: "text/yaml", body: "phases: [{duration_seconds: 1]"},
+		{name: "empty YAML", contentType: "application/x-yaml", body: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+
+			w := postScenario(scenariosMux(nil), contentType, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+			if resp := decodeJSON[map[string]any](t, w); resp["error"] != "Invalid scenario profile" {
+				t.Errorf("error = %v", resp["error"])
+			}
+		})
+	}
+}
+
+func TestScenarioPhaseLevels(t *testing.T) {
+	ramped := routes.ScenarioPhase{MemoryMB: 100, CPUCores: 2, CPUUtilizationPercent: 50, DurationS: 10, RampS: 4}
+	immediate := routes.ScenarioPhase{MemoryMB: 10, DurationS: 5}
+
+	tests := []struct {
+		name       string
+		phase      routes.ScenarioPhase
+		step       int
+		wantMemory int
+		wantLoad   float64
+	}{
+		{name: "first ramp step", phase: ramped, step: 0, wantMemory: 40, wantLoad: 175},
+		{name: "middle of the ramp", phase: ramped, step: 1, wantMemory: 60, wantLoad: 150},
+		{name: "end of the ramp", phase: ramped, step: 3, wantMemory: 100, wantLoad: 100},
+		{name: "after the ramp", phase: ramped, step: 9, wantMemory: 100, wantLoad: 100},
+		{name: "no ramp", phase: immediate, step: 0, wantMemory: 10, wantLoad: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// From a previous phase at 20 MB and two cores
+			memoryMB, load := routes.ScenarioPhaseLevels(tt.phase, 20, 200, tt.step)
+			if memoryMB != tt.wantMemory || load != tt.wantLoad {
+				t.Errorf("levels at step %d = %d MB, %v%%, want %d MB, %v%%", tt.step, memoryMB, load, tt.wantMemory, tt.wantLoad)
+			}
+		})
+	}
+}
+
+func TestScenarioFromYAML(t *testing.T) {
+	budget := routes.NewMemoryBudget(10, routes.AdmissionReject, 0)
+	mux := scenariosMux(budget)
+
+	profile := "name: short\nphases:\n  - {name: hold, memory_mb: 2, duration_seconds: 1}\n"
+	w := postScenario(mux, "application/yaml; charset=utf-8", profile)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("create: status = %d, want %d, body %q", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	status := decodeJSON[routes.ScenarioStatus](t, w)
+	if status.Name != "short" || len(status.Phases) != 1 || status.Phases[0].Name != "hold" || status.TotalSeconds != 1 {
+		t.Errorf("create: unexpected status %+v", status)
+	}
+
+	status = waitForScenario(t, mux, status.ID, finished)
+	if status.State != routes.JobStateCompleted || status.ProgressPercent != 100 || status.MemoryMB != 0 {
+		t.Errorf("finished scenario = %+v, want it completed without memory", status)
+	}
+	if used := budget.Usage().UsedMB; used != 0 {
+		t.Errorf("budget has %d MB used after the scenario", used)
+	}
+}
+
+func TestScenarioProgressWhileQueued(t *testing.T) {
+	budget := routes.NewMemoryBudget(1, routes.AdmissionQueue, time.Minute)
+	mux := scenariosMux(budget)
+	if err := budget.Acquire(t.Context(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	status := createScenario(t, mux, `{"phases": [{"memory_mb": 1, "duration_seconds": 1}]}`)
+
+	// Waiting for the budget longer than the phase lasts isn't progress
+	time.Sleep(1200 * time.Millisecond)
+	status = waitForScenario(t, mux, status.ID, func(routes.ScenarioStatus) bool { return true })
+	if status.State != routes.ScenarioStateRunning || status.ProgressPercent != 0 || status.ElapsedSeconds < 1 {
+		t.Errorf("queued scenario = %+v, want it running without progress", status)
+	}
+
+	budget.Release(1)
+	status = waitForScenario(t, mux, status.ID, finished)
+	if status.State != routes.JobStateCompleted || status.ProgressPercent != 100 {
+		t.Errorf("finished scenario = %+v, want it completed", status)
+	}
+}
+
+func TestAbortScenario(t *testing.T) {
+	budget := routes.NewMemoryBudget(10, routes.AdmissionReject, 0)
+	mux := scenariosMux(budget)
+
+	status := createScenario(t, mux, `{"phases": [{"memory_mb": 4, "cpu_cores": 1, "duration_seconds": 300}]}`)
+	if phase := status.Phases[0]; phase.CPUUtilizationPercent != 100 {
+		t.Errorf("cpu_utilization_percent = %d, want the default of 100", phase.CPUUtilizationPercent)
+	}
+	waitForBudget(t, budget, 4)
+
+	if w := postScenario(mux, "application/json", `{"phases": [{"duration_seconds": 1}]}`); w.Code != http.StatusConflict {
+		t.Errorf("second scenario: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w := serve(mux, newRequest(http.MethodDelete, "/api/admin/scenarios/"+status.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	status = decodeJSON[routes.ScenarioStatus](t, w)
+	if status.State != routes.JobStateCancelled || status.MemoryMB != 0 || status.CPULoadPercent != 0 || status.FinishedAt == "" {
+		t.Errorf("delete: scenario = %+v, want it cancelled without load", status)
+	}
+	if used := budget.Usage().UsedMB; used != 0 {
+		t.Errorf("budget has %d MB used after the abort", used)
+	}
+
+	if w := serve(mux, newRequest(http.MethodDelete, "/api/admin/scenarios/"+status.ID)); w.Code != http.StatusConflict {
+		t.Errorf("second delete: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := serve(mux, newRequest(http.MethodGet, "/api/admin/scenarios/unknown")); w.Code != http.StatusNotFound {
+		t.Errorf("unknown scenario: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestScenarioShutdown(t *testing.T) {
+	budget := routes.NewMemoryBudget(10, routes.AdmissionReject, 0)
+	mux := scenariosMux(budget)
+	t.Cleanup(routes.ResetAllocations)
+
+	status := createScenario(t, mux, `{"phases": [{"memory_mb": 4, "duration_seconds": 300}]}`)
+	waitForBudget(t, budget, 4)
+
+	routes.ReleaseActiveAllocations()
+	status = waitForScenario(t, mux, status.ID, finished)
+	if status.State != routes.JobStateCancelled || status.MemoryMB != 0 {
+		t.Errorf("scenario after the shutdown = %+v, want it cancelled without memory", status)
+	}
+	if used := budget.Usage().UsedMB; used != 0 {
+		t.Errorf("budget has %d MB used after the shutdown", used)
+	}
+
+	// Scenarios started during the shutdown end right away
+	status = createScenario(t, mux, `{"phases": [{"memory_mb": 4, "duration_seconds": 300}]}`)
+	if status = waitForScenario(t, mux, status.ID, finished); status.State != routes.JobStateCancelled {
+		t.Errorf("scenario started during the shutdown = %+v, want it cancelled", status)
+	}
+}
+
+func TestListScenariosOrder(t *testing.T) {
+	mux := scenariosMux(nil)
+
+	// IDs 9, 10 and 11 sort wrongly as strings
+	routes.SetLastRequestID(8)
+	want := make(map[string]int)
+	for i := range 3 {
+		status := createScenario(t, mux, `{"phases": [{"duration_seconds": 300}]}`)
+		serve(mux, newRequest(http.MethodDelete, "/api/admin/scenarios/"+status.ID))
+		want[status.ID] = i
+	}
+
+	w := serve(mux, newRequest(http.MethodGet, "/api/admin/scenarios"))
+	resp := decodeJSON[struct{ Scenarios []routes.ScenarioStatus }](t, w)
+	next := 0
+	for _, status := range resp.Scenarios {
+		i, ok := want[status.ID]
+		if !ok {
+			// Left over from other tests
+			continue
+		}
+		if i != next {
+			t.Fatalf("scenario %s listed at position %d, want %d", status.ID, next, i)
+		}
+		next++
+	}
+	if next != len(want) {
+		t.Errorf("listed %d of the %d scenarios", next, len(want))
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 // HandleGetVersion
 // /api/version
+// Responds with JSON or plain text depending on the Accept header.
 func HandleGetVersion(version string, authorClient *authorclient.Client, logger logger.Logger) http.HandlerFunc {
 	type Response struct {
 		QuoteService  string `json:"quote-service"`
@@ -16,16 +17,20 @@ func HandleGetVersion(version string, authorClient *authorclient.Client, logger
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		authorVersion, err := authorClient.GetVersion()
+		authorVersion, err := authorClient.GetVersion(r.Context())
 		if err != nil {
 			logger.ErrorWithCtx(r.Context(), "Failed to get author-service version", "error", err.Error())
 			http.Error(w, "Failed to get author-service version", http.StatusInternalServerError)
 			return
 		}
 
-		restapiutils.WriteJSONResponse(w, http.StatusOK, Response{
+		restapiutils.WriteResponse(w, r, http.StatusOK, Response{
 			QuoteService:  version,
 			AuthorService: authorVersion,
+		}, restapiutils.Representations{
+			Text: func() string {
+				return "quote-service " + version + "\nauthor-service " + authorVersion
+			},
 		})
 	}
 }
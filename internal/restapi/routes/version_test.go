@@ -0,0 +1,37 @@
+package routes_test
+
+import (
+	"net/http"
+	"quote-service/internal/restapi/routes"
+	"quote-service/pkg/logger/testlogger"
+	"testing"
+)
+
+func TestHandleGetVersion(t *testing.T) {
+	authorService := newFakeAuthorService(t)
+	authorService.version = "v2.3.4"
+	log := testlogger.New()
+	handler := routes.HandleGetVersion("v0.0.1", authorService.client(), log)
+
+	w := serve(handler, newRequest(http.MethodGet, "/api/version"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	resp := decodeJSON[map[string]string](t, w)
+	if resp["quote-service"] != "v0.0.1" || resp["author-service"] != "v2.3.4" {
+		t.Errorf("unexpected response %v", resp)
+	}
+
+	authorService.status = http.StatusInternalServerError
+	w = serve(handler, newRequest(http.MethodGet, "/api/version"))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	entry, ok := log.Find(testlogger.LevelError, "Failed to get author-service version")
+	if !ok {
+		t.Fatalf("failure not logged:\n%s", log)
+	}
+	if entry.TraceID != testTraceID || entry.String("error") == "" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,194 @@
+package restapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"quote-service/pkg/logger"
+	"sync"
+	"time"
+)
+
+// TLSConfig enables HTTPS when CertFile and KeyFile are set
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum accepted TLS version, "1.2" or "1.3"
+	MinVersion string
+
+	// ClientCAFile enables mTLS: client certificates are verified against the CAs in this file
+	ClientCAFile string
+	// ClientAuth is "require" (reject clients without a valid certificate, the default) or
+	// "verify-if-given" (only verify certificates that are presented). Ignored without
+	// ClientCAFile.
+	ClientAuth string
+
+	// ReloadInterval is how often the certificate files are checked for changes
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether HTTPS should be served
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// validate rejects partial configurations, which would otherwise silently serve plain HTTP
+func (c TLSConfig) validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("cert file and key file must be set together")
+	}
+	if !c.Enabled() && (c.ClientCAFile != "" || c.ClientAuth != "") {
+		return errors.New("client CA file and client auth require a cert file and key file")
+	}
+	return nil
+}
+
+var (
+	ErrUnknownTLSVersion    = errors.New("unknown TLS version")
+	ErrUnknownTLSClientAuth = errors.New("unknown TLS client auth mode")
+	ErrInvalidClientCA      = errors.New("no certificates found in client CA file")
+)
+
+// build creates the tls.Config served by the HTTP server. The certificate is reloaded from disk
+// when the files change so renewed certificates are picked up without a restart.
+func (c TLSConfig) build(logger logger.Logger) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(c.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := newCertReloader(c.CertFile, c.KeyFile, c.ReloadInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidClientCA, c.ClientCAFile)
+		}
+		config.ClientCAs = pool
+
+		switch c.ClientAuth {
+		case "", "require":
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		case "verify-if-given":
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTLSClientAuth, c.ClientAuth)
+		}
+	}
+
+	return config, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownTLSVersion, version)
+	}
+}
+
+// certReloader serves a certificate loaded from disk and reloads it when the certificate or key
+// file modification time changes. Files are checked at most once per interval, during handshakes.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   logger.Logger
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration, logger logger.Logger) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.interval > 0 && time.Since(r.lastCheck) >= r.interval {
+		r.lastCheck = time.Now()
+
+		if r.changed() {
+			// Keep serving the previous certificate if the new files are unusable, e.g. while
+			// they are being replaced
+			if err := r.reload(); err != nil {
+				r.logger.Error("Failed to reload TLS certificate", "error", err.Error())
+			} else {
+				r.logger.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+func (r *certReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
+
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat certificate file: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat key file: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.lastCheck = time.Now()
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package restapi
+
+import "testing"
+
+func TestTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TLSConfig
+		wantErr bool
+	}{
+		{"disabled", TLSConfig{}, false},
+		{"cert and key", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, false},
+		{"mTLS", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem", ClientAuth: "require"}, false},
+		{"cert only", TLSConfig{CertFile: "cert.pem"}, true},
+		{"key only", TLSConfig{KeyFile: "key.pem"}, true},
+		{"client CA without cert", TLSConfig{ClientCAFile: "ca.pem"}, true},
+		{"client auth without cert", TLSConfig{ClientAuth: "verify-if-given"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package restapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"quote-service/pkg/logger"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader   = "X-Request-ID"
+	traceparentHeader = "traceparent"
+
+	// maxRequestIDLength caps client-supplied request IDs so they can't bloat every log line
+	maxRequestIDLength = 128
+)
+
+// traceIDMiddleware makes sure every request carries a trace ID in its context. The ID is taken
+// from the client's X-Request-ID header, then from the request's OpenTelemetry span so logs and
+// traces correlate, then from the trace-id part of a W3C traceparent header, and is generated as a
+// last resort. It is echoed back in the X-Request-ID response header. When the span's trace ID is
+// a different one it's added to the context too, so logs carry both.
+func traceIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID := requestTraceID(r)
+
+		ctx := logger.WithTraceID(r.Context(), traceID)
+		if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+			if otelTraceID := spanCtx.TraceID().String(); otelTraceID != traceID {
+				ctx = logger.WithOtelTraceID(ctx, otelTraceID)
+			}
+		}
+
+		w.Header().Set(requestIDHeader, traceID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestTraceID picks the trace ID for an incoming request
+func requestTraceID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); isValidRequestID(id) {
+		return id
+	}
+
+	if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.HasTraceID() {
+		return spanCtx.TraceID().String()
+	}
+
+	if id, ok := traceIDFromTraceparent(r.Header.Get(traceparentHeader)); ok {
+		return id
+	}
+
+	return newTraceID()
+}
+
+// isValidRequestID accepts non-empty, reasonably short IDs made of printable ASCII characters
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// traceIDFromTraceparent extracts the trace-id field from a W3C traceparent header value
+// (version-traceid-parentid-flags).
+func traceIDFromTraceparent(header string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 {
+		return "", false
+	}
+
+	traceID := strings.ToLower(parts[1])
+	if _, err := hex.DecodeString(traceID); err != nil || traceID == strings.Repeat("0", 32) {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+// newTraceID generates a random 128-bit trace ID in the same hex format as W3C trace IDs
+func newTraceID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,111 @@
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quote-service/pkg/logger"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const spanTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestTraceIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          http.Header
+		withSpan        bool
+		wantTraceID     string
+		wantOtelTraceID string
+	}{
+		{
+			name:        "client request ID",
+			header:      http.Header{"X-Request-Id": {"client-id-1"}},
+			wantTraceID: "client-id-1",
+		},
+		{
+			name:            "client request ID with a span",
+			header:          http.Header{"X-Request-Id": {"client-id-1"}},
+			withSpan:        true,
+			wantTraceID:     "client-id-1",
+			wantOtelTraceID: spanTraceID,
+		},
+		{
+			name:        "span without a request ID",
+			withSpan:    true,
+			wantTraceID: spanTraceID,
+		},
+		{
+			name:        "invalid request ID falls back to the span",
+			header:      http.Header{"X-Request-Id": {"has space"}},
+			withSpan:    true,
+			wantTraceID: spanTraceID,
+		},
+		{
+			name:        "traceparent",
+			header:      http.Header{"Traceparent": {"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01"}},
+			wantTraceID: "0af7651916cd43dd8448eb211c80319c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTraceID, gotOtelTraceID string
+			handler := traceIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTraceID, gotOtelTraceID = logger.TraceID(r.Context()), logger.OtelTraceID(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/quote/random", nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			if tt.withSpan {
+				r = r.WithContext(trace.ContextWithSpanContext(r.Context(), newSpanContext(t)))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if gotTraceID != tt.wantTraceID {
+				t.Errorf("trace ID = %q, want %q", gotTraceID, tt.wantTraceID)
+			}
+			if gotOtelTraceID != tt.wantOtelTraceID {
+				t.Errorf("OpenTelemetry trace ID = %q, want %q", gotOtelTraceID, tt.wantOtelTraceID)
+			}
+			if got := w.Header().Get(requestIDHeader); got != tt.wantTraceID {
+				t.Errorf("X-Request-ID = %q, want %q", got, tt.wantTraceID)
+			}
+		})
+	}
+}
+
+func TestTraceIDMiddlewareGeneratesID(t *testing.T) {
+	var gotTraceID string
+	handler := traceIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceID = logger.TraceID(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/quote/random", nil))
+
+	if len(gotTraceID) != 32 {
+		t.Errorf("generated trace ID = %q, want 32 hex characters", gotTraceID)
+	}
+	if got := w.Header().Get(requestIDHeader); got != gotTraceID {
+		t.Errorf("X-Request-ID = %q, want %q", got, gotTraceID)
+	}
+}
+
+func newSpanContext(t *testing.T) trace.SpanContext {
+	t.Helper()
+
+	traceID, err := trace.TraceIDFromHex(spanTraceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+}
@@ -0,0 +1,91 @@
+package restapiutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a strong entity tag derived from parts, which should cover everything the response
+// body is rendered from
+func ETag(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		// Separates the parts so ("ab", "c") and ("a", "bc") get different tags
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// ETagVariant derives the entity tag of an alternate representation or content encoding, e.g.
+// "abc" becomes "abc-html"
+func ETagVariant(etag, variant string) string {
+	opaque, weak := strings.CutPrefix(etag, "W/")
+	if !strings.HasSuffix(opaque, `"`) || len(opaque) < 2 {
+		return etag
+	}
+
+	tag := strings.TrimSuffix(opaque, `"`) + "-" + variant + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// StripETagVariant removes the suffix added by ETagVariant from the tags of an If-None-Match
+// header value, so they can be compared with the tag of the base representation. It reports
+// whether any tag had the suffix.
+func StripETagVariant(ifNoneMatch, variant string) (string, bool) {
+	suffix := "-" + variant + `"`
+	candidates := strings.Split(ifNoneMatch, ",")
+	stripped := false
+	for i, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if base, ok := strings.CutSuffix(candidate, suffix); ok {
+			candidates[i] = base + `"`
+			stripped = true
+		}
+	}
+	return strings.Join(candidates, ", "), stripped
+}
+
+// etagMatches reports whether the If-None-Match header value contains etag, using the weak
+// comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified evaluates If-None-Match and, without it, If-Modified-Since against the ETag and
+// Last-Modified response headers. Only GET and HEAD requests are conditional.
+func notModified(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := w.Header().Get("ETag")
+		return etag != "" && etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
@@ -0,0 +1,153 @@
+package restapiutils
+
+import (
+	"encoding/csv"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Representations are the alternatives to JSON a response can be rendered as, negotiated from the
+// Accept header. Nil ones aren't offered.
+type Representations struct {
+	// Text renders the response as plain text
+	Text func() string
+	// HTML renders the response as an HTML snippet, values must be escaped
+	HTML func() string
+	// CSV renders a list response as CSV records, the first one being the header
+	CSV func() [][]string
+}
+
+// offer is a representation the response can be rendered as
+type offer struct {
+	mediaType   string
+	contentType string
+	// variant distinguishes the entity tag of the representation from the JSON one
+	variant string
+	write   func(w io.Writer)
+}
+
+// WriteResponse writes data as JSON, or as one of reps if the Accept header prefers it. Requests
+// that accept none of them get 406 listing the available media types.
+//
+// When the handler has set the ETag or Last-Modified header, successful responses to GET and HEAD
+// requests whose If-None-Match or If-Modified-Since conditions match are answered with 304. The
+// ETag of alternate representations gets a suffix so each representation has its own tag.
+func WriteResponse(w http.ResponseWriter, r *http.Request, statusCode int, data any, reps Representations) {
+	offers := []offer{{mediaType: "application/json", contentType: ContentTypeJSON}}
+	if reps.Text != nil {
+		offers = append(offers, offer{"text/plain", ContentTypeText, "text", func(w io.Writer) {
+			io.WriteString(w, reps.Text()+"\n")
+		}})
+	}
+	if reps.HTML != nil {
+		offers = append(offers, offer{"text/html", ContentTypeHTML, "html", func(w io.Writer) {
+			io.WriteString(w, reps.HTML()+"\n")
+		}})
+	}
+	if reps.CSV != nil {
+		offers = append(offers, offer{"text/csv", ContentTypeCSV, "csv", func(w io.Writer) {
+			csv.NewWriter(w).WriteAll(reps.CSV())
+		}})
+	}
+
+	if len(offers) > 1 {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	chosen, ok := negotiate(r.Header.Values("Accept"), offers)
+	if !ok {
+		mediaTypes := make([]string, len(offers))
+		for i, o := range offers {
+			mediaTypes[i] = o.mediaType
+		}
+		http.Error(w, "Not acceptable, available media types: "+strings.Join(mediaTypes, ", "), http.StatusNotAcceptable)
+		return
+	}
+
+	if etag := w.Header().Get("ETag"); etag != "" && chosen.variant != "" {
+		w.Header().Set("ETag", ETagVariant(etag, chosen.variant))
+	}
+	if statusCode == http.StatusOK && notModified(w, r) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if chosen.write == nil {
+		WriteJSONResponse(w, statusCode, data)
+		return
+	}
+	w.Header().Set("Content-Type", chosen.contentType)
+	w.WriteHeader(statusCode)
+	chosen.write(w)
+}
+
+// negotiate returns the offer with the highest quality in the Accept header values, the earliest
+// offer on ties. Without an Accept header the first offer is chosen.
+func negotiate(accept []string, offers []offer) (offer, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return offers[0], true
+	}
+
+	best, bestQuality := -1, 0.0
+	for i, o := range offers {
+		if q := quality(ranges, o.mediaType); q > bestQuality {
+			best, bestQuality = i, q
+		}
+	}
+	if best < 0 {
+		return offer{}, false
+	}
+	return offers[best], true
+}
+
+// mediaRange is an entry of the Accept header, like "text/*;q=0.5"
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept []string) []mediaRange {
+	var ranges []mediaRange
+	for _, header := range accept {
+		for entry := range strings.SplitSeq(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if qStr, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: q})
+		}
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching mediaType, 0 if none matches
+func quality(ranges []mediaRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, 0
+	for _, mr := range ranges {
+		s := 0
+		switch mr.mediaType {
+		case mediaType:
+			s = 3
+		case mainType + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = mr.quality, s
+		}
+	}
+	return q
+}
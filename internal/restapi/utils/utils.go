@@ -5,7 +5,16 @@ import (
 	"net/http"
 )
 
+// Content types of the representations written by this package
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeText = "text/plain; charset=utf-8"
+	ContentTypeHTML = "text/html; charset=utf-8"
+	ContentTypeCSV  = "text/csv; charset=utf-8"
+)
+
 func WriteJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 }
@@ -1,22 +1,33 @@
 package authorclient
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"quote-service/pkg/logger"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	breaker    *breaker
 }
 
 type NewClientConfig struct {
 	BaseURL string
 	Timeout time.Duration
+	// Transport is the underlying transport for author-service requests. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// Breaker opens the circuit after consecutive failures so the readiness check reports the
+	// author-service as down. Calls are never rejected by it.
+	Breaker BreakerConfig
 }
 
 type Author struct {
@@ -33,18 +44,74 @@ type AuthorsResponse struct {
 }
 
 func NewClient(config NewClientConfig) *Client {
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
 	return &Client{
 		baseURL: config.BaseURL,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
+			// Records a client span per request and propagates W3C trace context to the author-service
+			Transport: otelhttp.NewTransport(transport,
+				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return "author-service " + r.Method + " " + r.URL.Path
+				}),
+			),
 		},
+		breaker: newBreaker(config.Breaker),
+	}
+}
+
+// requestIDHeader is used to forward the caller's trace ID to the author-service
+const requestIDHeader = "X-Request-ID"
+
+// get sends a GET request to the given URL, forwarding the trace ID found in ctx. Its outcome is
+// recorded by the circuit breaker.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	resp, err := c.send(ctx, url)
+	if failure, ok := breakerOutcome(ctx, resp, err); ok {
+		c.breaker.record(failure)
+	}
+
+	return resp, err
+}
+
+// send sends a GET request to the given URL, forwarding the trace ID found in ctx
+func (c *Client) send(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if traceID := logger.TraceID(ctx); traceID != "" {
+		req.Header.Set(requestIDHeader, traceID)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// breakerOutcome returns the failure a call counts as for the circuit breaker, nil on success.
+// Transport errors and 5xx responses are failures. ok is false when the caller gave up, which
+// says nothing about the author-service.
+func breakerOutcome(ctx context.Context, resp *http.Response, err error) (failure error, ok bool) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, false
+	case err != nil:
+		return err, true
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode), true
+	default:
+		return nil, true
 	}
 }
 
-func (c *Client) GetVersion() (string, error) {
+func (c *Client) GetVersion(ctx context.Context) (string, error) {
 	url := fmt.Sprintf("%s/api/version", c.baseURL)
-	
-	resp, err := c.httpClient.Get(url)
+
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return "", fmt.Errorf("failed to get version: %w", err)
 	}
@@ -62,7 +129,41 @@ func (c *Client) GetVersion() (string, error) {
 	return versionResp.Version, nil
 }
 
-func (c *Client) GetAuthorsByIDs(ids []int) ([]Author, error) {
+// CircuitState returns the state of the circuit breaker: CircuitClosed, CircuitOpen or
+// CircuitHalfOpen
+func (c *Client) CircuitState() string {
+	state, _ := c.breaker.status()
+	return state
+}
+
+// CheckCircuit reports the circuit state as a readiness check: nil while the circuit is closed and
+// an error wrapping ErrCircuitOpen while it's open. The author-service is only called when the
+// circuit is half-open, as its trial call, so the circuit closes again even when no traffic
+// reaches the client.
+func (c *Client) CheckCircuit(ctx context.Context) error {
+	state, err := c.breaker.status()
+	if state != CircuitHalfOpen || !c.breaker.startTrial() {
+		return err
+	}
+
+	resp, err := c.send(ctx, fmt.Sprintf("%s/api/version", c.baseURL))
+	failure, ok := breakerOutcome(ctx, resp, err)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !ok {
+		c.breaker.cancelTrial()
+		return fmt.Errorf("author-service trial call: %w", err)
+	}
+
+	c.breaker.finishTrial(failure)
+	if failure != nil {
+		return fmt.Errorf("author-service trial call: %w", failure)
+	}
+	return nil
+}
+
+func (c *Client) GetAuthorsByIDs(ctx context.Context, ids []int) ([]Author, error) {
 	if len(ids) == 0 {
 		return []Author{}, nil
 	}
@@ -73,8 +174,8 @@ func (c *Client) GetAuthorsByIDs(ids []int) ([]Author, error) {
 	}
 
 	url := fmt.Sprintf("%s/api/authors/by-id?id=%s", c.baseURL, strings.Join(idsStr, ","))
-	
-	resp, err := c.httpClient.Get(url)
+
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get authors: %w", err)
 	}
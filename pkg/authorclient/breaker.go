@@ -0,0 +1,144 @@
+package authorclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is reported by the readiness check while the circuit is open
+var ErrCircuitOpen = errors.New("author-service circuit open")
+
+// BreakerConfig configures the circuit breaker that tracks the health of the author-service
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed calls that opens the circuit, 0 to
+	// never open it
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a trial call is made
+	OpenTimeout time.Duration
+}
+
+// breaker tracks the outcome of recent author-service calls for the readiness check, it never
+// rejects calls. After FailureThreshold consecutive failures it opens. Once OpenTimeout has passed
+// it's half-open and a single trial call decides its state: success closes the circuit and failure
+// opens it again. Calls made while the circuit isn't closed don't change its state.
+type breaker struct {
+	config BreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	lastErr  error
+	// trialRunning is set while the half-open trial call is in flight
+	trialRunning bool
+}
+
+func newBreaker(config BreakerConfig) *breaker {
+	return &breaker{config: config, state: CircuitClosed}
+}
+
+// currentState returns the state, moving from open to half-open once OpenTimeout has passed. The
+// caller must hold mu.
+func (b *breaker) currentState(now time.Time) string {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = CircuitHalfOpen
+	}
+	return b.state
+}
+
+// record reports the outcome of a regular call, err is nil on success. It's ignored unless the
+// circuit is closed.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.currentState(time.Now()) != CircuitClosed {
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		b.lastErr = nil
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	if b.config.FailureThreshold > 0 && b.failures >= b.config.FailureThreshold {
+		b.open()
+	}
+}
+
+// startTrial reports whether the caller may make the trial call, i.e. the circuit is half-open
+// and no other trial is in flight. The outcome of the trial must be reported with finishTrial or
+// cancelTrial.
+func (b *breaker) startTrial() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.currentState(time.Now()) != CircuitHalfOpen || b.trialRunning {
+		return false
+	}
+	b.trialRunning = true
+	return true
+}
+
+// finishTrial reports the outcome of the trial call: nil closes the circuit, an error opens it
+// again
+func (b *breaker) finishTrial(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialRunning = false
+	if err == nil {
+		b.state = CircuitClosed
+		b.failures = 0
+		b.lastErr = nil
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	b.open()
+}
+
+// cancelTrial gives up the trial whose outcome says nothing about the author-service, e.g.
+// because the caller went away
+func (b *breaker) cancelTrial() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialRunning = false
+}
+
+// open opens the circuit. The caller must hold mu.
+func (b *breaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = time.Now()
+}
+
+// openError describes why the circuit is open. The caller must hold mu.
+func (b *breaker) openError() error {
+	return fmt.Errorf("%w after %d consecutive failures, last: %v", ErrCircuitOpen, b.failures, b.lastErr)
+}
+
+// status returns the state and, unless the circuit is closed, the error that opened it
+func (b *breaker) status() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.currentState(time.Now())
+	if state == CircuitClosed {
+		return state, nil
+	}
+	return state, b.openError()
+}
@@ -0,0 +1,156 @@
+package authorclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(`{"version":"v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(NewClientConfig{
+		BaseURL: server.URL,
+		Timeout: time.Second,
+		Breaker: BreakerConfig{FailureThreshold: 3, OpenTimeout: 50 * time.Millisecond},
+	})
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.GetVersion(ctx); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("GetVersion() error = %v, want a failed call", err)
+		}
+	}
+	if state := client.CircuitState(); state != CircuitOpen {
+		t.Fatalf("state after 3 failures = %s, want %s", state, CircuitOpen)
+	}
+
+	// Open: calls still reach the author-service, readiness checks don't
+	if _, err := client.GetVersion(ctx); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("GetVersion() error = %v, want a failed call", err)
+	}
+	if err := client.CheckCircuit(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("CheckCircuit() error = %v, want ErrCircuitOpen", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("author-service called %d times, want 4", n)
+	}
+
+	// Half-open: regular calls don't decide the state, only the trial does
+	time.Sleep(60 * time.Millisecond)
+	if state := client.CircuitState(); state != CircuitHalfOpen {
+		t.Fatalf("state after the timeout = %s, want %s", state, CircuitHalfOpen)
+	}
+	status.Store(http.StatusOK)
+	if _, err := client.GetVersion(ctx); err != nil {
+		t.Fatalf("GetVersion() error = %v", err)
+	}
+	if state := client.CircuitState(); state != CircuitHalfOpen {
+		t.Fatalf("state after a regular call = %s, want %s", state, CircuitHalfOpen)
+	}
+	status.Store(http.StatusInternalServerError)
+
+	// The failed trial opens the circuit again
+	if err := client.CheckCircuit(ctx); err == nil {
+		t.Error("CheckCircuit() succeeded with a failing trial call")
+	}
+	if state := client.CircuitState(); state != CircuitOpen {
+		t.Fatalf("state after a failed trial = %s, want %s", state, CircuitOpen)
+	}
+
+	// A successful trial closes it
+	status.Store(http.StatusOK)
+	time.Sleep(60 * time.Millisecond)
+	if err := client.CheckCircuit(ctx); err != nil {
+		t.Errorf("CheckCircuit() error = %v with a succeeding trial call", err)
+	}
+	if state := client.CircuitState(); state != CircuitClosed {
+		t.Fatalf("state after a successful trial = %s, want %s", state, CircuitClosed)
+	}
+
+	// Closed: readiness checks don't call the author-service
+	before := calls.Load()
+	if err := client.CheckCircuit(ctx); err != nil {
+		t.Errorf("CheckCircuit() error = %v while closed", err)
+	}
+	if calls.Load() != before {
+		t.Error("CheckCircuit() called the author-service while the circuit is closed")
+	}
+}
+
+func TestBreakerSingleTrial(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(`{"version":"v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(NewClientConfig{BaseURL: server.URL, Timeout: time.Second})
+	client.breaker = newBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Millisecond})
+	client.breaker.record(errors.New("connection refused"))
+	time.Sleep(2 * time.Millisecond)
+
+	trial := make(chan error)
+	go func() { trial <- client.CheckCircuit(context.Background()) }()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A second probe while the trial is in flight doesn't call the author-service
+	if err := client.CheckCircuit(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("CheckCircuit() during the trial error = %v, want ErrCircuitOpen", err)
+	}
+	// Nor does a regular call that succeeds meanwhile close the circuit
+	client.breaker.record(nil)
+	if state := client.CircuitState(); state != CircuitHalfOpen {
+		t.Errorf("state during the trial = %s, want %s", state, CircuitHalfOpen)
+	}
+
+	close(release)
+	if err := <-trial; err != nil {
+		t.Errorf("trial CheckCircuit() error = %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("author-service called %d times, want 1", n)
+	}
+	if state := client.CircuitState(); state != CircuitClosed {
+		t.Errorf("state after the trial = %s, want %s", state, CircuitClosed)
+	}
+}
+
+func TestBreakerIgnoresClientErrorsAndCancellation(t *testing.T) {
+	b := newBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	client := NewClient(NewClientConfig{BaseURL: server.URL, Timeout: time.Second})
+	client.breaker = b
+
+	if _, err := client.GetVersion(context.Background()); err == nil {
+		t.Fatal("GetVersion() succeeded on 404")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.GetVersion(ctx)
+
+	if state := client.CircuitState(); state != CircuitClosed {
+		t.Errorf("state = %s, want %s after a 404 and a cancelled call", state, CircuitClosed)
+	}
+}
@@ -0,0 +1,73 @@
+// Package field provides typed key-value pairs for the *Fields methods of logger.Logger, so
+// numbers, booleans and durations keep their type in structured output.
+package field
+
+import "time"
+
+// ErrorKey is the key of fields created by Err
+const ErrorKey = "error"
+
+// Field is a key-value pair attached to a log record
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields is the value of a field created by Group
+type Fields []Field
+
+// String creates a string field
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int creates an integer field
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: int64(value)}
+}
+
+// Int64 creates an integer field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Uint64 creates an unsigned integer field
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Float64 creates a floating point field
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool creates a boolean field
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration creates a duration field. JSON output renders it in nanoseconds, text output as a
+// duration string like "1.5s".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Time creates a timestamp field
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err creates an "error" field holding the message of err
+func Err(err error) Field {
+	return Field{Key: ErrorKey, Value: err}
+}
+
+// Group nests fields under key, e.g. {"budget": {"used_mb": 20, "limit_mb": 100}} in JSON output
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, Value: Fields(fields)}
+}
+
+// Any creates a field of any value. Prefer the typed constructors.
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
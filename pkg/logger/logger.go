@@ -1,11 +1,17 @@
 package logger
 
-import "context"
+import (
+	"context"
+	"quote-service/pkg/logger/field"
+)
 
 // contextKey is an unexported type for context keys to avoid collisions with other packages
 type contextKey string
 
-const traceIDKey contextKey = "traceID"
+const (
+	traceIDKey     contextKey = "traceID"
+	otelTraceIDKey contextKey = "otelTraceID"
+)
 
 // TraceID extracts the trace ID from the context. Returns empty string if not found.
 func TraceID(ctx context.Context) string {
@@ -20,6 +26,21 @@ func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, traceIDKey, traceID)
 }
 
+// OtelTraceID extracts the OpenTelemetry trace ID from the context. Returns empty string if not
+// found.
+func OtelTraceID(ctx context.Context) string {
+	if traceID, ok := ctx.Value(otelTraceIDKey).(string); ok {
+		return traceID
+	}
+	return ""
+}
+
+// WithOtelTraceID adds the trace ID of the request's OpenTelemetry span to the context. It's
+// logged next to the trace ID when the two differ, e.g. when the client sent its own request ID.
+func WithOtelTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, otelTraceIDKey, traceID)
+}
+
 // Logger is a simple logging interface. Each method accepts a message and optional key-value pairs
 // for structured logging.
 type Logger interface {
@@ -54,4 +75,42 @@ type Logger interface {
 	// ErrorWithCtx logs ERROR level messages with context. It's up to the implementation to decide
 	// what to do with the context (e.g., extract trace IDs). Arguments are key-value pairs.
 	ErrorWithCtx(ctx context.Context, msg string, keysAndValues ...string)
+
+	// DebugFields logs DEBUG level messages with typed fields. Like the *WithCtx methods it's up to
+	// the implementation to decide what to do with the context. Example:
+	//  logger.DebugFields(ctx, "Cache miss", field.Int("size", 3), field.Duration("took", d))
+	DebugFields(ctx context.Context, msg string, fields ...field.Field)
+
+	// InfoFields logs INFO level messages with typed fields. Example:
+	//  logger.InfoFields(ctx, "Memory allocated", field.Int("memory_mb", 100))
+	InfoFields(ctx context.Context, msg string, fields ...field.Field)
+
+	// WarnFields logs WARN level messages with typed fields. Example:
+	//  logger.WarnFields(ctx, "Retrying", field.Int("attempt", 2), field.Err(err))
+	WarnFields(ctx context.Context, msg string, fields ...field.Field)
+
+	// ErrorFields logs ERROR level messages with typed fields. Example:
+	//  logger.ErrorFields(ctx, "Operation failed", field.Err(err))
+	ErrorFields(ctx context.Context, msg string, fields ...field.Field)
+
+	// With returns a child logger that adds fields to every message, e.g.
+	//  jobLogger := logger.With(field.String("job_id", id))
+	With(fields ...field.Field) Logger
+
+	// Named returns a logger for component. Its level can be overridden separately from the base
+	// level, e.g.
+	//  routesLogger := logger.Named("routes")
+	Named(component string) Logger
+}
+
+// LevelController changes log levels at runtime. Levels are names like "debug", "info", "warn"
+// and "error".
+type LevelController interface {
+	// Levels returns the base level and the per-component overrides
+	Levels() (level string, components map[string]string)
+
+	// SetLevels changes the base level, unless level is empty, and the overrides of the given
+	// components. An empty component level removes its override. Nothing changes if any level is
+	// invalid.
+	SetLevels(level string, components map[string]string) error
 }
@@ -0,0 +1,118 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/lmittmann/tint"
+)
+
+// Log formats supported by NewLogger
+const (
+	FormatJSON  = "json"
+	FormatColor = "color"
+	// FormatText and FormatLogfmt both write key=value lines
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+	// FormatGCP is JSON with the field names of Google Cloud Logging
+	FormatGCP = "gcp"
+	// FormatECS is JSON with the field names of the Elastic Common Schema
+	FormatECS = "ecs"
+)
+
+// Formats lists the valid values of NewLoggerArgs.LogFormat
+var Formats = []string{FormatJSON, FormatColor, FormatText, FormatLogfmt, FormatGCP, FormatECS}
+
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// ecsVersion is the version of the Elastic Common Schema the ecs format follows
+const ecsVersion = "8.11.0"
+
+// newFormatHandler creates the handler writing records in format to w. It logs records of all
+// levels, filtering happens in front of it.
+func newFormatHandler(format string, w io.Writer) (slog.Handler, error) {
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}), nil //nolint:exhaustruct
+	case FormatColor:
+		return tint.NewHandler(w, &tint.Options{Level: slog.LevelDebug}), nil //nolint:exhaustruct
+	case FormatText, FormatLogfmt:
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}), nil //nolint:exhaustruct
+	case FormatGCP:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: replaceGCPAttr}), nil //nolint:exhaustruct
+	case FormatECS:
+		handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: replaceECSAttr}) //nolint:exhaustruct
+		return handler.WithAttrs([]slog.Attr{slog.String("ecs.version", ecsVersion)}), nil
+	default:
+		return nil, fmt.Errorf("%w %q, must be one of %s", ErrUnknownFormat, format, strings.Join(Formats, ", "))
+	}
+}
+
+// replaceGCPAttr renames the built-in fields to the ones Cloud Logging recognizes in structured
+// logs
+func replaceGCPAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.MessageKey:
+		attr.Key = "message"
+	case slog.LevelKey:
+		// Only the built-in level holds a slog.Level, attributes named "level" are kept as is
+		level, ok := attr.Value.Any().(slog.Level)
+		if !ok {
+			return attr
+		}
+		attr.Key = "severity"
+		attr.Value = slog.StringValue(gcpSeverity(level))
+	case traceIDKey:
+		attr.Key = "trace_id"
+	case otelTraceIDKey:
+		attr.Key = "otel_trace_id"
+	}
+	return attr
+}
+
+// gcpSeverity maps a level to a Cloud Logging severity
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// replaceECSAttr renames the built-in fields to their Elastic Common Schema names
+func replaceECSAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "@timestamp"
+	case slog.MessageKey:
+		attr.Key = "message"
+	case slog.LevelKey:
+		level, ok := attr.Value.Any().(slog.Level)
+		if !ok {
+			return attr
+		}
+		attr.Key = "log.level"
+		attr.Value = slog.StringValue(strings.ToLower(level.String()))
+	case traceIDKey:
+		attr.Key = "trace.id"
+	case componentKey:
+		attr.Key = "log.logger"
+	}
+	return attr
+}
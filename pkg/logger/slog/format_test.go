@@ -0,0 +1,36 @@
+package slog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatUserLevelAttr(t *testing.T) {
+	tests := []struct {
+		format   string
+		levelKey string
+		want     string
+	}{
+		{FormatGCP, "severity", "WARNING"},
+		{FormatECS, "log.level", "warn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			l, buf := newTestLogger(t, tt.format)
+			// Logged by the log-level admin route, must not be taken for the built-in level
+			l.Warn("Log levels changed", "level", "debug")
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if entry[tt.levelKey] != tt.want {
+				t.Errorf("%s = %v, want %q", tt.levelKey, entry[tt.levelKey], tt.want)
+			}
+			if entry["level"] != "debug" {
+				t.Errorf("level = %v, want the attribute value %q", entry["level"], "debug")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+)
+
+// Level is the level of log records. The zero value is INFO.
+type Level = slog.Level
+
+// ParseLevel parses a level name like "debug" or "WARN"
+func ParseLevel(level string) (Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", level)
+	}
+	return l, nil
+}
+
+// levels holds the base level and the per-component overrides shared by a logger and all its
+// named loggers
+type levels struct {
+	base slog.LevelVar
+
+	mu         sync.RWMutex
+	components map[string]slog.Level
+}
+
+func newLevels(base slog.Level, components map[string]slog.Level) *levels {
+	lv := &levels{components: make(map[string]slog.Level, len(components))}
+	lv.base.Set(base)
+	maps.Copy(lv.components, components)
+	return lv
+}
+
+// enabled reports whether records of level are logged for component
+func (lv *levels) enabled(component string, level slog.Level) bool {
+	lv.mu.RLock()
+	min, ok := lv.components[component]
+	lv.mu.RUnlock()
+
+	if !ok {
+		min = lv.base.Level()
+	}
+	return level >= min
+}
+
+// levelHandler filters records by the level of its component before passing them to the format
+// handler, which logs everything
+type levelHandler struct {
+	next      slog.Handler
+	levels    *levels
+	component string
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.levels.enabled(h.component, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.next.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), levels: h.levels, component: h.component}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), levels: h.levels, component: h.component}
+}
@@ -0,0 +1,222 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RedactedValue replaces redacted values and matches
+const RedactedValue = "[REDACTED]"
+
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	bearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// RedactionConfig selects what is masked in log messages and attributes before they reach the
+// format handler
+type RedactionConfig struct {
+	// Keys are attribute keys whose values are masked. They match case-insensitively with "-"
+	// and "_" treated alike, and also as the suffix of longer keys, so "token" covers
+	// "access_token".
+	Keys []string
+	// Emails and BearerTokens mask email addresses and "Bearer <token>" credentials wherever they
+	// appear in messages and string values
+	Emails       bool
+	BearerTokens bool
+	// Patterns are additional regular expressions masked like Emails
+	Patterns []string
+}
+
+// redactor applies a RedactionConfig
+type redactor struct {
+	keys     []string
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(config RedactionConfig) (*redactor, error) {
+	r := &redactor{}
+	for _, key := range config.Keys {
+		r.keys = append(r.keys, normalizeKey(key))
+	}
+
+	if config.Emails {
+		r.patterns = append(r.patterns, emailPattern)
+	}
+	if config.BearerTokens {
+		r.patterns = append(r.patterns, bearerTokenPattern)
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+func (r *redactor) empty() bool {
+	return len(r.keys) == 0 && len(r.patterns) == 0
+}
+
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "-", "_")
+}
+
+// redactsKey reports whether the value of key is masked
+func (r *redactor) redactsKey(key string) bool {
+	key = normalizeKey(key)
+	return slices.ContainsFunc(r.keys, func(redacted string) bool {
+		return key == redacted || strings.HasSuffix(key, "_"+redacted)
+	})
+}
+
+// redactString masks all pattern matches in s
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
+
+func (r *redactor) redactAttr(attr slog.Attr) slog.Attr {
+	if r.redactsKey(attr.Key) {
+		return slog.String(attr.Key, RedactedValue)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, r.redactString(value.String()))
+	case slog.KindGroup:
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(r.redactAttrs(value.Group())...)}
+	case slog.KindAny:
+		return slog.Any(attr.Key, r.redactAny(value.Any(), 0))
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// maxRedactDepth bounds the recursion into nested values, deeper values are rendered as text
+const maxRedactDepth = 8
+
+// redactAny masks sensitive data in an arbitrary value. Maps with string keys, slices and arrays,
+// e.g. an http.Header, are copied with their keys and elements redacted like attributes. Other
+// values, including structs, are rendered as text when that text contains a pattern match; struct
+// field names aren't matched against the keys, so secrets in structs are only masked by patterns.
+func (r *redactor) redactAny(v any, depth int) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.redactString(v)
+	case error, fmt.Stringer, []byte:
+		// Rendered as text from Error or String, keeping the value when nothing matches
+		return r.redactText(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	if depth >= maxRedactDepth {
+		return r.redactText(v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return r.redactText(v)
+		}
+		redacted := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if r.redactsKey(key) {
+				redacted[key] = RedactedValue
+			} else {
+				redacted[key] = r.redactAny(iter.Value().Interface(), depth+1)
+			}
+		}
+		return redacted
+	case reflect.Slice, reflect.Array:
+		redacted := make([]any, rv.Len())
+		for i := range redacted {
+			redacted[i] = r.redactAny(rv.Index(i).Interface(), depth+1)
+		}
+		return redacted
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return v
+		}
+		return r.redactAny(rv.Elem().Interface(), depth+1)
+	default:
+		return r.redactText(v)
+	}
+}
+
+// redactText returns v rendered as text with the pattern matches masked, or v unchanged if
+// nothing matches, so values keep their format handler rendering unless they leak something
+func (r *redactor) redactText(v any) any {
+	if len(r.patterns) == 0 {
+		return v
+	}
+	text := fmt.Sprint(v)
+	if redacted := r.redactString(text); redacted != text {
+		return redacted
+	}
+	return v
+}
+
+func (r *redactor) redactAttrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = r.redactAttr(attr)
+	}
+	return redacted
+}
+
+// redactHandler masks sensitive data in records before passing them to the format handler
+type redactHandler struct {
+	next     slog.Handler
+	redactor *redactor
+}
+
+// newRedactHandler wraps next with redaction, or returns it as is if nothing is redacted
+func newRedactHandler(next slog.Handler, config RedactionConfig) (slog.Handler, error) {
+	r, err := newRedactor(config)
+	if err != nil {
+		return nil, err
+	}
+	if r.empty() {
+		return next, nil
+	}
+
+	return &redactHandler{next: next, redactor: r}, nil
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.redactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.redactAttr(attr))
+		return true
+	})
+
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &redactHandler{next: h.next.WithAttrs(h.redactor.redactAttrs(attrs)), redactor: h.redactor}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
@@ -0,0 +1,238 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRedaction = RedactionConfig{
+	Keys:         []string{"authorization", "api_key", "token"},
+	Emails:       true,
+	BearerTokens: true,
+	Patterns:     []string{`sk-[a-z0-9]{8}`},
+}
+
+// newTestLogger creates a logger writing format to a buffer with testRedaction
+func newTestLogger(t *testing.T, format string) (*Logger, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	handler, err := newFormatHandler(format, &buf)
+	if err != nil {
+		t.Fatalf("newFormatHandler(%q): %v", format, err)
+	}
+	handler, err = newRedactHandler(handler, testRedaction)
+	if err != nil {
+		t.Fatalf("newRedactHandler: %v", err)
+	}
+
+	return newLoggerWithHandler(handler, "", newLevels(slog.LevelDebug, nil)), &buf
+}
+
+// secrets are the values that must never show up in the output of redactionCases
+var secrets = []string{"s3cr3t", "jane@example.com", "eyJhbGciOiJIUzI1NiJ9.payload.sig", "sk-abcdef12"}
+
+var redactionCases = []struct {
+	name string
+	log  func(l logger.Logger)
+}{
+	{
+		name: "key in string pairs",
+		log:  func(l logger.Logger) { l.Info("Request received", "authorization", "s3cr3t") },
+	},
+	{
+		name: "key with other case and separator",
+		log:  func(l logger.Logger) { l.Warn("Request received", "X-API-Key", "s3cr3t") },
+	},
+	{
+		name: "key as suffix",
+		log:  func(l logger.Logger) { l.Info("Token issued", "access_token", "s3cr3t") },
+	},
+	{
+		name: "email in message",
+		log:  func(l logger.Logger) { l.Info("Quote submitted by jane@example.com") },
+	},
+	{
+		name: "email in value",
+		log:  func(l logger.Logger) { l.InfoWithCtx(context.Background(), "Quote submitted", "author", "jane@example.com") },
+	},
+	{
+		name: "bearer token in value",
+		log: func(l logger.Logger) {
+			l.Error("Authentication failed", "header", "Bearer eyJhbGciOiJIUzI1NiJ9.payload.sig")
+		},
+	},
+	{
+		name: "custom pattern",
+		log:  func(l logger.Logger) { l.Debug("Using key sk-abcdef12") },
+	},
+	{
+		name: "typed fields in a group",
+		log: func(l logger.Logger) {
+			l.InfoFields(context.Background(), "Request",
+				field.Group("headers", field.String("Authorization", "s3cr3t"), field.String("from", "jane@example.com")))
+		},
+	},
+	{
+		name: "error field",
+		log: func(l logger.Logger) {
+			l.ErrorFields(context.Background(), "Lookup failed", field.Err(errors.New("no user jane@example.com")))
+		},
+	},
+	{
+		name: "http.Header",
+		log: func(l logger.Logger) {
+			header := http.Header{"Authorization": {"s3cr3t"}, "From": {"jane@example.com"}}
+			l.InfoFields(context.Background(), "Request", field.Any("headers", header))
+		},
+	},
+	{
+		name: "nested map and slice",
+		log: func(l logger.Logger) {
+			l.InfoFields(context.Background(), "Request", field.Any("request", map[string]any{
+				"auth":       map[string]string{"api_key": "s3cr3t"},
+				"recipients": []string{"jane@example.com"},
+			}))
+		},
+	},
+	{
+		name: "child logger fields",
+		log: func(l logger.Logger) {
+			l.With(field.String("token", "s3cr3t")).Named("routes").Info("Child logger")
+		},
+	},
+}
+
+func TestRedactionJSON(t *testing.T) {
+	for _, tc := range redactionCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, buf := newTestLogger(t, FormatJSON)
+			tc.log(l)
+
+			output := buf.String()
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("output is not valid JSON: %s", output)
+			}
+			assertRedacted(t, output)
+		})
+	}
+}
+
+func TestRedactionColor(t *testing.T) {
+	for _, tc := range redactionCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, buf := newTestLogger(t, FormatColor)
+			tc.log(l)
+
+			assertRedacted(t, buf.String())
+		})
+	}
+}
+
+func TestRedactionKeepsOtherData(t *testing.T) {
+	l, buf := newTestLogger(t, FormatJSON)
+	ctx := logger.WithOtelTraceID(logger.WithTraceID(context.Background(), "abc123"), "def456")
+	l.InfoWithCtx(ctx, "Quote served", "quote_id", "42", "total_seconds", "7")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("unmarshal output: %v", err)
+	}
+	want := map[string]string{"msg": "Quote served", "traceID": "abc123", "otelTraceID": "def456",
+		"quote_id": "42", "total_seconds": "7"}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %q", key, record[key], value)
+		}
+	}
+}
+
+func TestRedactionKeepsHeaderStructure(t *testing.T) {
+	l, buf := newTestLogger(t, FormatJSON)
+	header := http.Header{"Accept": {"text/html"}, "X-Api-Key": {"s3cr3t"}}
+	l.InfoFields(context.Background(), "Request", field.Any("headers", header))
+
+	var record struct {
+		Headers map[string]any `json:"headers"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("unmarshal output: %v", err)
+	}
+	if accept, _ := record.Headers["Accept"].([]any); len(accept) != 1 || accept[0] != "text/html" {
+		t.Errorf("Accept = %v, want [text/html]", record.Headers["Accept"])
+	}
+	if record.Headers["X-Api-Key"] != RedactedValue {
+		t.Errorf("X-Api-Key = %v, want %q", record.Headers["X-Api-Key"], RedactedValue)
+	}
+}
+
+func TestRedactionInvalidPattern(t *testing.T) {
+	if _, err := newRedactor(RedactionConfig{Patterns: []string{"("}}); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func assertRedacted(t *testing.T, output string) {
+	t.Helper()
+
+	if output == "" {
+		t.Fatal("nothing was logged")
+	}
+	for _, secret := range secrets {
+		if strings.Contains(output, secret) {
+			t.Errorf("output contains %q: %s", secret, output)
+		}
+	}
+	if !strings.Contains(output, RedactedValue) {
+		t.Errorf("output doesn't contain %q: %s", RedactedValue, output)
+	}
+}
+
+// contact is a fmt.Stringer rendering an email address
+type contact string
+
+func (c contact) String() string {
+	return "contact " + string(c)
+}
+
+func TestRedactAnyKeepsValuesWithoutMatches(t *testing.T) {
+	r, err := newRedactor(testRedaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type credentials struct {
+		User  string
+		Email string
+	}
+	notFound := errors.New("quote not found")
+	tests := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{name: "error", value: notFound, want: notFound},
+		{name: "error with a match", value: errors.New("no user jane@example.com"), want: "no user " + RedactedValue},
+		{name: "stringer", value: 1500 * time.Millisecond, want: 1500 * time.Millisecond},
+		{name: "stringer with a match", value: contact("jane@example.com"), want: "contact " + RedactedValue},
+		{name: "struct", value: credentials{User: "jane"}, want: credentials{User: "jane"}},
+		{name: "struct with a match", value: credentials{Email: "jane@example.com"}, want: "{ " + RedactedValue + "}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.redactAny(tt.value, 0); got != tt.want {
+				t.Errorf("redactAny(%v) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package slog
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotationTimeFormat is appended to the path of rotated files, it sorts chronologically. Files
+// rotated within the same millisecond get a "-N" suffix.
+const rotationTimeFormat = "20060102T150405.000"
+
+// FileSinkConfig configures a log file that is rotated by size and age
+type FileSinkConfig struct {
+	Path string
+	// MaxSizeMB rotates the file before it grows past this size, 0 for no limit
+	MaxSizeMB int
+	// RotationInterval rotates the file once it's been written to for this long, 0 to disable
+	RotationInterval time.Duration
+	// MaxBackups is the number of rotated files kept, 0 to keep all of them
+	MaxBackups int
+	// MaxAge deletes rotated files older than this, 0 to keep them regardless of age
+	MaxAge time.Duration
+}
+
+// rotatingFile is an io.WriteCloser appending to a file that is renamed to
+// "<path>.<timestamp>" when it's rotated
+type rotatingFile struct {
+	config FileSinkConfig
+
+	mu sync.Mutex
+	// file is nil after Close, or when it couldn't be reopened during a rotation, in which case
+	// the next write tries again
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+}
+
+func newRotatingFile(config FileSinkConfig) (*rotatingFile, error) {
+	if config.Path == "" {
+		return nil, errors.New("log file path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &rotatingFile{config: config}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file for appending. The caller must hold mu, except in newRotatingFile.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	var rotateErr error
+	if f.shouldRotate(len(p)) {
+		// The record is written even if the rotation fails, as long as a file is open
+		if rotateErr = f.rotate(); f.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// shouldRotate reports whether writing n more bytes needs a new file. An empty file is never
+// rotated so a single large record can't cause endless rotations.
+func (f *rotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	maxSize := int64(f.config.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && f.size+int64(n) > maxSize {
+		return true
+	}
+	return f.config.RotationInterval > 0 && time.Since(f.openedAt) >= f.config.RotationInterval
+}
+
+// rotate renames the current file, opens a new one and deletes old backups. When the rename
+// fails the current file is reopened and the next write tries again. The caller must hold mu.
+func (f *rotatingFile) rotate() error {
+	closeErr := f.file.Close()
+	f.file = nil
+
+	renameErr := os.Rename(f.config.Path, f.backupPath(time.Now()))
+	openedAt := f.openedAt
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		f.openedAt = openedAt
+		return fmt.Errorf("failed to rotate log file: %w", renameErr)
+	}
+
+	f.removeOldBackups()
+	if closeErr != nil {
+		return fmt.Errorf("failed to close log file: %w", closeErr)
+	}
+	return nil
+}
+
+// backupPath returns an unused path for a file rotated at now
+func (f *rotatingFile) backupPath(now time.Time) string {
+	base := f.config.Path + "." + now.Format(rotationTimeFormat)
+	path := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); err != nil {
+			return path
+		}
+		path = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// backup is a rotated file, ordered by rotation time and then suffix
+type backup struct {
+	path      string
+	rotatedAt time.Time
+	seq       int
+}
+
+// parseBackup parses the path of a rotated file, reporting false for other files
+func (f *rotatingFile) parseBackup(path string) (backup, bool) {
+	stamp, seqStr, hasSeq := strings.Cut(strings.TrimPrefix(path, f.config.Path+"."), "-")
+	rotatedAt, err := time.Parse(rotationTimeFormat, stamp)
+	if err != nil {
+		return backup{}, false
+	}
+
+	seq := 0
+	if hasSeq {
+		if seq, err = strconv.Atoi(seqStr); err != nil || seq < 1 {
+			return backup{}, false
+		}
+	}
+	return backup{path: path, rotatedAt: rotatedAt, seq: seq}, true
+}
+
+// removeOldBackups applies MaxBackups and MaxAge. Failures are ignored, they only leave extra
+// files behind.
+func (f *rotatingFile) removeOldBackups() {
+	if f.config.MaxBackups <= 0 && f.config.MaxAge <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(f.config.Path + ".*")
+	if err != nil {
+		return
+	}
+	var backups []backup
+	for _, path := range matches {
+		if b, ok := f.parseBackup(path); ok {
+			backups = append(backups, b)
+		}
+	}
+	// Newest first
+	slices.SortFunc(backups, func(a, b backup) int {
+		if c := b.rotatedAt.Compare(a.rotatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.seq, a.seq)
+	})
+
+	for i, b := range backups {
+		tooMany := f.config.MaxBackups > 0 && i >= f.config.MaxBackups
+		tooOld := false
+		if f.config.MaxAge > 0 {
+			if info, err := os.Stat(b.path); err == nil {
+				tooOld = time.Since(info.ModTime()) > f.config.MaxAge
+			}
+		}
+		if tooMany || tooOld {
+			os.Remove(b.path)
+		}
+	}
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
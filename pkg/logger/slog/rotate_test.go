@@ -0,0 +1,281 @@
+package slog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestRotatingFile creates a rotating file in a temporary directory
+func newTestRotatingFile(t *testing.T, config FileSinkConfig) *rotatingFile {
+	t.Helper()
+
+	config.Path = filepath.Join(t.TempDir(), "logs", "app.log")
+	f, err := newRotatingFile(config)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// backups returns the rotated files of f, oldest first
+func backups(t *testing.T, f *rotatingFile) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(f.config.Path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+func writeOrFail(t *testing.T, f *rotatingFile, p []byte) {
+	t.Helper()
+
+	if _, err := f.Write(p); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+// rotateOrFail writes a line after a pause long enough for a distinct backup name, rotating by
+// interval
+func rotateOrFail(t *testing.T, f *rotatingFile, line string) {
+	t.Helper()
+
+	time.Sleep(f.config.RotationInterval + 5*time.Millisecond)
+	writeOrFail(t, f, []byte(line))
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{MaxSizeMB: 1})
+
+	chunk := bytes.Repeat([]byte("a"), 600*1024)
+	writeOrFail(t, f, chunk)
+	if got := backups(t, f); len(got) != 0 {
+		t.Fatalf("rotated below the size limit: %v", got)
+	}
+
+	writeOrFail(t, f, chunk)
+	got := backups(t, f)
+	if len(got) != 1 {
+		t.Fatalf("backups = %v, want one after passing the size limit", got)
+	}
+	assertFileSize(t, got[0], len(chunk))
+	assertFileSize(t, f.config.Path, len(chunk))
+}
+
+func TestRotatingFileSizeLargeRecord(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{MaxSizeMB: 1})
+
+	// A record larger than the limit goes to the empty file instead of rotating it
+	writeOrFail(t, f, bytes.Repeat([]byte("a"), 2*1024*1024))
+	if got := backups(t, f); len(got) != 0 {
+		t.Fatalf("an empty file was rotated: %v", got)
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{RotationInterval: 20 * time.Millisecond})
+
+	writeOrFail(t, f, []byte("first\n"))
+	writeOrFail(t, f, []byte("second\n"))
+	if got := backups(t, f); len(got) != 0 {
+		t.Fatalf("rotated before the interval: %v", got)
+	}
+
+	rotateOrFail(t, f, "third\n")
+	got := backups(t, f)
+	if len(got) != 1 {
+		t.Fatalf("backups = %v, want one after the interval", got)
+	}
+	assertFileContent(t, got[0], "first\nsecond\n")
+	assertFileContent(t, f.config.Path, "third\n")
+}
+
+func TestRotatingFileMaxBackups(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{RotationInterval: 10 * time.Millisecond, MaxBackups: 2})
+
+	writeOrFail(t, f, []byte("1\n"))
+	for _, line := range []string{"2\n", "3\n", "4\n", "5\n"} {
+		rotateOrFail(t, f, line)
+	}
+
+	got := backups(t, f)
+	if len(got) != 2 {
+		t.Fatalf("backups = %v, want 2", got)
+	}
+	// The newest backups are kept
+	assertFileContent(t, got[0], "3\n")
+	assertFileContent(t, got[1], "4\n")
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{RotationInterval: 10 * time.Millisecond, MaxAge: time.Hour})
+
+	dir := filepath.Dir(f.config.Path)
+	old := filepath.Join(dir, "app.log."+time.Now().Add(-2*time.Hour).Format(rotationTimeFormat))
+	recent := filepath.Join(dir, "app.log."+time.Now().Add(-30*time.Minute).Format(rotationTimeFormat))
+	unrelated := filepath.Join(dir, "app.log.bak")
+	for _, path := range []string{old, recent, unrelated} {
+		if err := os.WriteFile(path, []byte("backup\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	twoHoursAgo := time.Now().Add(-2 * time.Hour)
+	for _, path := range []string{old, unrelated} {
+		if err := os.Chtimes(path, twoHoursAgo, twoHoursAgo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeOrFail(t, f, []byte("1\n"))
+	rotateOrFail(t, f, "2\n")
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("backup older than MaxAge wasn't removed: %v", err)
+	}
+	for _, path := range []string{recent, unrelated} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", filepath.Base(path), err)
+		}
+	}
+	if got := backups(t, f); len(got) != 3 {
+		t.Errorf("files = %v, want the recent backup, the new one and the unrelated file", got)
+	}
+}
+
+// rotateNow rotates f regardless of its size and age
+func rotateNow(t *testing.T, f *rotatingFile) {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+}
+
+func TestRotatingFileSameMillisecond(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{})
+
+	// Rotations in a row mostly fall into the same millisecond
+	lines := []string{"1\n", "2\n", "3\n"}
+	for _, line := range lines {
+		writeOrFail(t, f, []byte(line))
+		rotateNow(t, f)
+	}
+
+	got := backups(t, f)
+	if len(got) != len(lines) {
+		t.Fatalf("backups = %v, want %d", got, len(lines))
+	}
+	for i, path := range got {
+		assertFileContent(t, path, lines[i])
+	}
+}
+
+func TestRotatingFileMaxBackupsWithSuffixes(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{MaxBackups: 2})
+
+	base := f.config.Path + "." + time.Now().Add(-time.Minute).Format(rotationTimeFormat)
+	for _, path := range []string{base, base + "-1", base + "-2", base + "-10"} {
+		if err := os.WriteFile(path, []byte("backup\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeOrFail(t, f, []byte("1\n"))
+	rotateNow(t, f)
+
+	// The new backup and the one with the highest suffix are the newest
+	got := backups(t, f)
+	if len(got) != 2 || got[0] != base+"-10" {
+		t.Errorf("backups = %v, want the new one and %s", got, filepath.Base(base+"-10"))
+	}
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{RotationInterval: 10 * time.Millisecond})
+	writeOrFail(t, f, []byte("1\n"))
+
+	// Renaming fails once the file is gone, the record goes to a new file instead
+	if err := os.Remove(f.config.Path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(f.config.RotationInterval + 5*time.Millisecond)
+	if _, err := f.Write([]byte("2\n")); err == nil {
+		t.Error("Write didn't report the failed rotation")
+	}
+	assertFileContent(t, f.config.Path, "2\n")
+
+	// The next rotation works again
+	rotateOrFail(t, f, "3\n")
+	got := backups(t, f)
+	if len(got) != 1 {
+		t.Fatalf("backups = %v, want one", got)
+	}
+	assertFileContent(t, got[0], "2\n")
+	assertFileContent(t, f.config.Path, "3\n")
+}
+
+func TestRotatingFileReopenFailure(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{RotationInterval: 10 * time.Millisecond})
+	writeOrFail(t, f, []byte("1\n"))
+
+	// Without its directory, neither renaming nor reopening the file works
+	dir := filepath.Dir(f.config.Path)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(f.config.RotationInterval + 5*time.Millisecond)
+	if _, err := f.Write([]byte("2\n")); err == nil {
+		t.Fatal("Write succeeded without a log directory")
+	}
+
+	// Writes reopen the file once the directory is back
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeOrFail(t, f, []byte("3\n"))
+	assertFileContent(t, f.config.Path, "3\n")
+}
+
+func TestRotatingFileWriteAfterClose(t *testing.T) {
+	f := newTestRotatingFile(t, FileSinkConfig{})
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func assertFileSize(t *testing.T, path string, want int) {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(want) {
+		t.Errorf("%s is %d bytes, want %d", filepath.Base(path), info.Size(), want)
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), content, want)
+	}
+}
@@ -0,0 +1,195 @@
+package slog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// suppressedMessage is the message of the summaries of sampled out records
+const suppressedMessage = "Suppressed similar log messages"
+
+// SamplingPolicy limits how often records with the same level and message are logged within an
+// interval
+type SamplingPolicy struct {
+	// First is the number of records logged per interval before sampling starts
+	First int
+	// Thereafter logs 1 in Thereafter of the records after First, 0 drops all of them
+	Thereafter int
+}
+
+// SamplingConfig configures the sampling of repeated messages, e.g. an error logged by every
+// request while a dependency is down. Records that are dropped are counted and reported at the end
+// of the interval in a summary record of the same level.
+type SamplingConfig struct {
+	// Interval is the period after which counts reset and summaries are logged, sampling is
+	// disabled when it's 0
+	Interval time.Duration
+	// Levels are the sampled levels, records of other levels are always logged
+	Levels map[Level]SamplingPolicy
+}
+
+func (c SamplingConfig) validate() error {
+	if c.Interval < 0 {
+		return errors.New("sampling interval must not be negative")
+	}
+	for level, policy := range c.Levels {
+		if policy.First < 0 || policy.Thereafter < 0 {
+			return errors.New("sampling of " + level.String() + " must not be negative")
+		}
+	}
+	return nil
+}
+
+// samplingKey identifies similar records
+type samplingKey struct {
+	level   slog.Level
+	message string
+}
+
+type samplingCount struct {
+	logged     int
+	seen       int
+	suppressed int
+}
+
+// sampler holds the counts of the current interval. It's shared by all handlers derived from the
+// same samplingHandler.
+type sampler struct {
+	config SamplingConfig
+	// summaries receives the summary records, it has no attributes added by WithAttrs
+	summaries slog.Handler
+
+	mu     sync.Mutex
+	counts map[samplingKey]*samplingCount
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newSamplingHandler wraps next with sampling. It returns next unchanged and a nil sampler when
+// sampling is disabled. The sampler must be closed to stop logging summaries.
+func newSamplingHandler(next slog.Handler, config SamplingConfig) (slog.Handler, *sampler, error) {
+	if err := config.validate(); err != nil {
+		return nil, nil, err
+	}
+	if config.Interval == 0 || len(config.Levels) == 0 {
+		return next, nil, nil
+	}
+
+	s := &sampler{
+		config:    config,
+		summaries: next,
+		counts:    make(map[samplingKey]*samplingCount),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+
+	return &samplingHandler{next: next, sampler: s}, s, nil
+}
+
+// allow counts record and reports whether it should be logged
+func (s *sampler) allow(record slog.Record) bool {
+	policy, ok := s.config.Levels[record.Level]
+	if !ok {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := samplingKey{level: record.Level, message: record.Message}
+	count, ok := s.counts[key]
+	if !ok {
+		count = &samplingCount{}
+		s.counts[key] = count
+	}
+
+	count.seen++
+	allowed := count.seen <= policy.First ||
+		policy.Thereafter > 0 && (count.seen-policy.First)%policy.Thereafter == 0
+	if allowed {
+		count.logged++
+	} else {
+		count.suppressed++
+	}
+
+	return allowed
+}
+
+// run resets the counts and logs the summaries every interval until the sampler is closed
+func (s *sampler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.stop:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush logs a summary for every message that had records dropped and starts a new interval
+func (s *sampler) flush() {
+	s.mu.Lock()
+	counts := s.counts
+	s.counts = make(map[samplingKey]*samplingCount, len(counts))
+	s.mu.Unlock()
+
+	now := time.Now()
+	for key, count := range counts {
+		if count.suppressed == 0 {
+			continue
+		}
+
+		record := slog.NewRecord(now, key.level, suppressedMessage, 0)
+		record.AddAttrs(
+			slog.String("sampled_message", key.message),
+			slog.Int("suppressed", count.suppressed),
+			slog.Int("logged", count.logged),
+			slog.String("interval", s.config.Interval.String()),
+		)
+		s.summaries.Handle(context.Background(), record)
+	}
+}
+
+// Close logs the last summaries and stops the sampler
+func (s *sampler) Close() error {
+	close(s.stop)
+	<-s.done
+	return nil
+}
+
+// samplingHandler drops records its sampler doesn't allow
+type samplingHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.sampler.allow(record) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,83 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newFormatHandler(FormatJSON, &buf)
+	if err != nil {
+		t.Fatalf("newFormatHandler: %v", err)
+	}
+	// The interval is long enough for the summary to be logged by Close only
+	handler, sampler, err := newSamplingHandler(handler, SamplingConfig{
+		Interval: time.Hour,
+		Levels:   map[Level]SamplingPolicy{slog.LevelError: {First: 5, Thereafter: 10}},
+	})
+	if err != nil {
+		t.Fatalf("newSamplingHandler: %v", err)
+	}
+	l := newLoggerWithHandler(handler, "", newLevels(slog.LevelDebug, nil))
+
+	for range 25 {
+		l.Error("Failed to get author", "error", "connection refused")
+	}
+	for range 3 {
+		l.Info("Request handled")
+	}
+	l.Named("routes").Error("Author not found")
+	sampler.Close()
+
+	counts := make(map[string]int)
+	var summary map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		msg := record["msg"].(string)
+		counts[msg]++
+		if msg == suppressedMessage {
+			summary = record
+		}
+	}
+
+	// Records 1-5, 15 and 25 are logged
+	want := map[string]int{"Failed to get author": 7, "Request handled": 3, "Author not found": 1, suppressedMessage: 1}
+	for msg, n := range want {
+		if counts[msg] != n {
+			t.Errorf("%q logged %d times, want %d\n%s", msg, counts[msg], n, buf.String())
+		}
+	}
+
+	if summary["level"] != "ERROR" || summary["sampled_message"] != "Failed to get author" ||
+		summary["suppressed"] != 18.0 || summary["logged"] != 7.0 {
+		t.Errorf("unexpected summary %v", summary)
+	}
+}
+
+func TestSamplingDisabled(t *testing.T) {
+	next := slog.DiscardHandler
+	for _, config := range []SamplingConfig{
+		{},
+		{Interval: time.Second},
+		{Levels: map[Level]SamplingPolicy{slog.LevelError: {First: 1}}},
+	} {
+		handler, sampler, err := newSamplingHandler(next, config)
+		if err != nil || sampler != nil || handler != next {
+			t.Errorf("config %+v: sampling enabled", config)
+		}
+	}
+
+	if _, _, err := newSamplingHandler(next, SamplingConfig{
+		Interval: time.Second,
+		Levels:   map[Level]SamplingPolicy{slog.LevelWarn: {First: -1}},
+	}); err == nil {
+		t.Error("negative policy accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package slog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Sink types
+const (
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+)
+
+var ErrUnknownSink = errors.New("unknown log sink")
+
+// SinkConfig configures one destination of the logs
+type SinkConfig struct {
+	// Type is SinkStderr, SinkFile or SinkSyslog
+	Type string
+	// Format is one of Formats, NewLoggerArgs.LogFormat when empty
+	Format string
+	// Level is the minimum level written to this sink on top of the logger's levels, nil to write
+	// everything the logger lets through
+	Level *Level
+
+	File   FileSinkConfig
+	Syslog SyslogSinkConfig
+}
+
+// sink is a format handler with its own minimum level and the resources to release on Close
+type sink struct {
+	handler slog.Handler
+	level   *Level
+	closer  io.Closer
+}
+
+// newSink opens the destination of config and creates its format handler
+func newSink(config SinkConfig, defaultFormat string) (*sink, error) {
+	format := config.Format
+	if format == "" {
+		format = defaultFormat
+	}
+
+	switch config.Type {
+	case SinkStderr:
+		handler, err := newFormatHandler(format, os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		return &sink{handler: handler, level: config.Level}, nil
+	case SinkFile:
+		file, err := newRotatingFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		handler, err := newFormatHandler(format, file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &sink{handler: handler, level: config.Level, closer: file}, nil
+	case SinkSyslog:
+		writer, err := newSyslogWriter(config.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		handler, err := newFormatHandler(format, writer)
+		if err != nil {
+			writer.Close()
+			return nil, err
+		}
+		return &sink{handler: newSyslogHandler(handler, writer), level: config.Level, closer: writer}, nil
+	default:
+		return nil, fmt.Errorf("%w %q, must be one of %s, %s, %s", ErrUnknownSink, config.Type, SinkStderr, SinkFile, SinkSyslog)
+	}
+}
+
+func (s *sink) enabled(level slog.Level) bool {
+	return s.level == nil || level >= *s.level
+}
+
+// fanoutHandler passes records to every sink whose level they reach
+type fanoutHandler struct {
+	sinks []*sink
+}
+
+func (h *fanoutHandler) Enabled(_ context.Context, level slog.Level) bool {
+	for _, s := range h.sinks {
+		if s.enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, s := range h.sinks {
+		if s.enabled(record.Level) {
+			errs = append(errs, s.handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.derive(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	return h.derive(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *fanoutHandler) derive(fn func(slog.Handler) slog.Handler) *fanoutHandler {
+	sinks := make([]*sink, len(h.sinks))
+	for i, s := range h.sinks {
+		sinks[i] = &sink{handler: fn(s.handler), level: s.level, closer: s.closer}
+	}
+	return &fanoutHandler{sinks: sinks}
+}
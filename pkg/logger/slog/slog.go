@@ -2,34 +2,186 @@ package slog
 
 import (
 	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
-	"os"
+)
 
-	"github.com/lmittmann/tint"
+// Keys of the attributes added by the logger
+const (
+	traceIDKey     = "traceID"
+	otelTraceIDKey = "otelTraceID"
+	componentKey   = "component"
 )
 
 type Logger struct {
 	logger *slog.Logger
+	// handler formats records of all levels, level filtering happens in front of it. It includes
+	// the fields added by With but not the component.
+	handler   slog.Handler
+	component string
+	levels    *levels
+	// sinks and sampler are closed by Close, they're shared by all loggers derived from the same
+	// NewLogger
+	sinks   []*sink
+	sampler *sampler
 }
 
-var _ logger.Logger = (*Logger)(nil)
+var (
+	_ logger.Logger          = (*Logger)(nil)
+	_ logger.LevelController = (*Logger)(nil)
+)
 
 type NewLoggerArgs struct {
 	LogFormat string
+	// Level is the minimum level of logged records, INFO by default
+	Level Level
+	// ComponentLevels overrides Level for the loggers returned by Named
+	ComponentLevels map[string]Level
+	// Redaction masks sensitive data in every record, nothing is masked when it's empty
+	Redaction RedactionConfig
+	// Sinks are the destinations of the logs, stderr in LogFormat when it's empty
+	Sinks []SinkConfig
+	// Sampling limits repeated messages, nothing is sampled when it's empty
+	Sampling SamplingConfig
+}
+
+// NewLogger creates a logger writing to the configured sinks. It returns ErrUnknownFormat if
+// LogFormat or the format of a sink isn't one of Formats.
+func NewLogger(args NewLoggerArgs) (*Logger, error) {
+	configs := args.Sinks
+	if len(configs) == 0 {
+		configs = []SinkConfig{{Type: SinkStderr}}
+	}
+
+	// Sinks log everything at or above their own level, the logger's levels are checked by
+	// levelHandler so they can change at runtime
+	sinks := make([]*sink, 0, len(configs))
+	for _, config := range configs {
+		s, err := newSink(config, args.LogFormat)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, fmt.Errorf("%s sink: %w", config.Type, err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	handler, err := newRedactHandler(&fanoutHandler{sinks: sinks}, args.Redaction)
+	if err != nil {
+		closeSinks(sinks)
+		return nil, err
+	}
+
+	// Sampling comes before redaction so the summaries, which repeat the message, are redacted too
+	handler, sampler, err := newSamplingHandler(handler, args.Sampling)
+	if err != nil {
+		closeSinks(sinks)
+		return nil, err
+	}
+
+	l := newLoggerWithHandler(handler, "", newLevels(args.Level, args.ComponentLevels))
+	l.sinks = sinks
+	l.sampler = sampler
+	return l, nil
+}
+
+// Close logs the pending sampling summaries and closes the log file and syslog connection of the
+// sinks. Loggers derived with Named and With must not be used afterwards.
+func (l *Logger) Close() error {
+	if l.sampler != nil {
+		l.sampler.Close()
+	}
+	return closeSinks(l.sinks)
 }
 
-func NewLogger(args NewLoggerArgs) *Logger {
-	var logger *slog.Logger
+func closeSinks(sinks []*sink) error {
+	var errs []error
+	for _, s := range sinks {
+		if s.closer != nil {
+			errs = append(errs, s.closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newLoggerWithHandler creates a logger for component writing to handler
+func newLoggerWithHandler(handler slog.Handler, component string, levels *levels) *Logger {
+	next := handler
+	if component != "" {
+		next = handler.WithAttrs([]slog.Attr{slog.String(componentKey, component)})
+	}
 
-	switch args.LogFormat {
-	case "json":
-		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{})) //nolint:exhaustruct
-	case "color":
-		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{})) //nolint:exhaustruct
+	return &Logger{
+		logger:    slog.New(&levelHandler{next: next, levels: levels, component: component}),
+		handler:   handler,
+		component: component,
+		levels:    levels,
 	}
+}
+
+// Named implements logger.Logger. Records of the returned logger carry a "component" attribute.
+func (l *Logger) Named(component string) logger.Logger {
+	return newLoggerWithHandler(l.handler, component, l.levels)
+}
 
-	return &Logger{logger: logger}
+// With implements logger.Logger.
+func (l *Logger) With(fields ...field.Field) logger.Logger {
+	return newLoggerWithHandler(l.handler.WithAttrs(fieldsToAttrs(fields)), l.component, l.levels)
+}
+
+// Levels implements logger.LevelController.
+func (l *Logger) Levels() (string, map[string]string) {
+	l.levels.mu.RLock()
+	defer l.levels.mu.RUnlock()
+
+	components := make(map[string]string, len(l.levels.components))
+	for component, level := range l.levels.components {
+		components[component] = level.String()
+	}
+	return l.levels.base.Level().String(), components
+}
+
+// SetLevels implements logger.LevelController.
+func (l *Logger) SetLevels(level string, components map[string]string) error {
+	var base *slog.Level
+	if level != "" {
+		parsed, err := ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		base = &parsed
+	}
+
+	overrides := make(map[string]*slog.Level, len(components))
+	for component, componentLevel := range components {
+		if componentLevel == "" {
+			overrides[component] = nil
+			continue
+		}
+		parsed, err := ParseLevel(componentLevel)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		overrides[component] = &parsed
+	}
+
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+
+	if base != nil {
+		l.levels.base.Set(*base)
+	}
+	for component, override := range overrides {
+		if override == nil {
+			delete(l.levels.components, component)
+			continue
+		}
+		l.levels.components[component] = *override
+	}
+
+	return nil
 }
 
 // Debug implements logger.Logger.
@@ -72,16 +224,78 @@ func (l *Logger) WarnWithCtx(ctx context.Context, msg string, keysAndValues ...s
 	l.logger.Warn(msg, l.withTraceID(ctx, keysAndValues)...)
 }
 
-// withTraceID extracts trace ID from context and prepends it to key-value pairs
+// DebugFields implements logger.Logger.
+func (l *Logger) DebugFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.logger.LogAttrs(ctx, slog.LevelDebug, msg, l.attrsWithTraceID(ctx, fields)...)
+}
+
+// InfoFields implements logger.Logger.
+func (l *Logger) InfoFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.logger.LogAttrs(ctx, slog.LevelInfo, msg, l.attrsWithTraceID(ctx, fields)...)
+}
+
+// WarnFields implements logger.Logger.
+func (l *Logger) WarnFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.logger.LogAttrs(ctx, slog.LevelWarn, msg, l.attrsWithTraceID(ctx, fields)...)
+}
+
+// ErrorFields implements logger.Logger.
+func (l *Logger) ErrorFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.logger.LogAttrs(ctx, slog.LevelError, msg, l.attrsWithTraceID(ctx, fields)...)
+}
+
+// attrsWithTraceID converts fields to attributes, prepending the trace IDs of the context
+func (l *Logger) attrsWithTraceID(ctx context.Context, fields []field.Field) []slog.Attr {
+	traceID, otelTraceID := logger.TraceID(ctx), logger.OtelTraceID(ctx)
+	if traceID == "" && otelTraceID == "" {
+		return fieldsToAttrs(fields)
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields)+2) //nolint:mnd
+	if traceID != "" {
+		attrs = append(attrs, slog.String(traceIDKey, traceID))
+	}
+	if otelTraceID != "" {
+		attrs = append(attrs, slog.String(otelTraceIDKey, otelTraceID))
+	}
+	return append(attrs, fieldsToAttrs(fields)...)
+}
+
+func fieldsToAttrs(fields []field.Field) []slog.Attr {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = fieldToAttr(f)
+	}
+
+	return attrs
+}
+
+func fieldToAttr(f field.Field) slog.Attr {
+	switch value := f.Value.(type) {
+	case field.Fields:
+		return slog.Attr{Key: f.Key, Value: slog.GroupValue(fieldsToAttrs(value)...)}
+	case error:
+		return slog.String(f.Key, value.Error())
+	default:
+		return slog.Any(f.Key, value)
+	}
+}
+
+// withTraceID extracts the trace IDs from context and prepends them to key-value pairs
 func (l *Logger) withTraceID(ctx context.Context, keysAndValues []string) []any {
-	traceID := logger.TraceID(ctx)
-	if traceID == "" {
+	traceID, otelTraceID := logger.TraceID(ctx), logger.OtelTraceID(ctx)
+	if traceID == "" && otelTraceID == "" {
 		return stringsToAnySlice(keysAndValues)
 	}
 
-	// Prepend traceID to the key-value pairs
-	args := make([]any, 0, len(keysAndValues)+2) //nolint:mnd
-	args = append(args, "traceID", traceID)
+	// Prepend the trace IDs to the key-value pairs
+	args := make([]any, 0, len(keysAndValues)+4) //nolint:mnd
+	if traceID != "" {
+		args = append(args, traceIDKey, traceID)
+	}
+	if otelTraceID != "" {
+		args = append(args, otelTraceIDKey, otelTraceID)
+	}
 	args = append(args, stringsToAnySlice(keysAndValues)...)
 
 	return args
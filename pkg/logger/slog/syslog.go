@@ -0,0 +1,281 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// syslogDialTimeout bounds connecting to the syslog server
+	syslogDialTimeout = 5 * time.Second
+	// syslogWriteTimeout bounds sending one message, so a stalled server can't block the sender
+	syslogWriteTimeout = 2 * time.Second
+	// syslogQueueSize is the number of messages buffered for the sender. Messages logged while
+	// the queue is full are dropped.
+	syslogQueueSize = 1024
+	// syslogMinBackoff and syslogMaxBackoff bound the wait between reconnection attempts, which
+	// doubles after every failed attempt
+	syslogMinBackoff = 500 * time.Millisecond
+	syslogMaxBackoff = 30 * time.Second
+)
+
+// Syslog severities used by the sink
+const (
+	syslogSeverityError   = 3
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+	syslogSeverityDebug   = 7
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogSinkConfig configures an RFC 5424 syslog destination
+type SyslogSinkConfig struct {
+	// Network is "udp" or "tcp". TCP messages are framed with octet counting (RFC 6587).
+	Network string
+	// Address is the host:port of the syslog server
+	Address string
+	// Facility is a name like "user" or "local0", "user" when empty
+	Facility string
+	// AppName is the APP-NAME of the messages
+	AppName string
+}
+
+// syslogWriter turns every write into one RFC 5424 message and queues it for a background
+// sender, so logging never waits for the network. The sender drops messages while the queue is
+// full or the server is unreachable, and reports how many it dropped once it's connected again.
+// The severity of the message is set by syslogHandler before the format handler writes the
+// record.
+type syslogWriter struct {
+	config   SyslogSinkConfig
+	facility int
+	hostname string
+	pid      string
+
+	// mu is held by syslogHandler while the format handler writes, and by Close
+	mu       sync.Mutex
+	severity int
+	queue    chan []byte
+
+	// dropped counts the messages lost since the last report
+	dropped atomic.Int64
+	// closing is set by Close. It rejects writes and stops reconnecting, so Close doesn't wait for
+	// an unreachable server.
+	closing atomic.Bool
+	// done is closed once the sender has stopped
+	done chan struct{}
+
+	// conn is only used by the sender
+	conn net.Conn
+}
+
+func newSyslogWriter(config SyslogSinkConfig) (*syslogWriter, error) {
+	if config.Network != "udp" && config.Network != "tcp" {
+		return nil, fmt.Errorf("syslog network must be udp or tcp, got %q", config.Network)
+	}
+	if config.Address == "" {
+		return nil, errors.New("syslog address is required")
+	}
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q: %w", config.Address, err)
+	}
+
+	facility := syslogFacilities["user"]
+	if config.Facility != "" {
+		var ok bool
+		if facility, ok = syslogFacilities[config.Facility]; !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", config.Facility)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{
+		config:   config,
+		facility: facility,
+		hostname: hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+		queue:    make(chan []byte, syslogQueueSize),
+		done:     make(chan struct{}),
+	}
+	// The sender connects with the first message, so an unreachable server doesn't delay startup
+	go w.run()
+
+	return w, nil
+}
+
+func (w *syslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.config.Network, w.config.Address, syslogDialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write queues p, a formatted record, as the MSG of one syslog message. The caller must hold mu.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	if w.closing.Load() {
+		return 0, os.ErrClosed
+	}
+
+	select {
+	case w.queue <- w.format(w.severity, bytes.TrimRight(p, "\n")):
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// run sends the queued messages until the queue is closed. A message that fails is retried once
+// on a new connection. When that fails too, reconnecting is retried with exponential backoff and
+// messages are dropped in the meantime.
+func (w *syslogWriter) run() {
+	defer close(w.done)
+
+	var backoff time.Duration
+	var retryAt time.Time
+	for msg := range w.queue {
+		if w.conn == nil && time.Now().Before(retryAt) {
+			w.dropped.Add(1)
+			continue
+		}
+
+		if err := w.sendWithRetry(msg); err != nil {
+			w.dropped.Add(1)
+			backoff = min(max(2*backoff, syslogMinBackoff), syslogMaxBackoff)
+			retryAt = time.Now().Add(backoff)
+			continue
+		}
+		backoff = 0
+
+		if dropped := w.dropped.Swap(0); dropped > 0 {
+			notice := fmt.Sprintf("Dropped %d log messages while the syslog server was unreachable or slow", dropped)
+			w.send(w.format(syslogSeverityWarning, []byte(notice)))
+		}
+	}
+
+	if w.conn != nil {
+		w.conn.Close()
+	}
+}
+
+// sendWithRetry sends msg, reconnecting once if there's no connection or it broke
+func (w *syslogWriter) sendWithRetry(msg []byte) error {
+	if w.conn != nil && w.send(msg) == nil {
+		return nil
+	}
+	if w.closing.Load() {
+		return os.ErrClosed
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	return w.send(msg)
+}
+
+// send writes msg to the connection, closing it on failure
+func (w *syslogWriter) send(msg []byte) error {
+	w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := w.conn.Write(msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format builds "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG"
+func (w *syslogWriter) format(severity int, body []byte) []byte {
+	appName := w.config.AppName
+	if appName == "" {
+		appName = "-"
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "<%d>1 %s %s %s %s - - ", w.facility*8+severity,
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, appName, w.pid)
+	msg.Write(body)
+
+	if w.config.Network == "tcp" {
+		return append([]byte(strconv.Itoa(msg.Len())+" "), msg.Bytes()...)
+	}
+	return msg.Bytes()
+}
+
+// Close sends the queued messages and closes the connection. Messages are dropped once a send
+// fails, so it waits at most syslogWriteTimeout for a stalled server.
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	if w.closing.Swap(true) {
+		w.mu.Unlock()
+		return nil
+	}
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// syslogSeverity maps a level to an RFC 5424 severity
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return syslogSeverityError
+	case level >= slog.LevelWarn:
+		return syslogSeverityWarning
+	case level >= slog.LevelInfo:
+		return syslogSeverityInfo
+	default:
+		return syslogSeverityDebug
+	}
+}
+
+// syslogHandler sets the severity of the syslog message before the format handler writes the
+// record. Handlers derived with WithAttrs and WithGroup share the writer and its lock.
+type syslogHandler struct {
+	next   slog.Handler
+	writer *syslogWriter
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.writer.mu.Lock()
+	defer h.writer.mu.Unlock()
+
+	h.writer.severity = syslogSeverity(record.Level)
+	return h.next.Handle(ctx, record)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{next: h.next.WithAttrs(attrs), writer: h.writer}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{next: h.next.WithGroup(name), writer: h.writer}
+}
+
+func newSyslogHandler(next slog.Handler, writer *syslogWriter) *syslogHandler {
+	return &syslogHandler{next: next, writer: writer}
+}
@@ -0,0 +1,237 @@
+package slog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syslogHeader matches "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG"
+var syslogHeader = regexp.MustCompile(`^<(\d+)>1 (\S+) (\S+) (\S+) (\S+) - - (.*)$`)
+
+// newTestSyslogLogger logs JSON to a syslog server at address
+func newTestSyslogLogger(t *testing.T, network, address string) (*slog.Logger, *syslogWriter) {
+	t.Helper()
+
+	writer, err := newSyslogWriter(SyslogSinkConfig{Network: network, Address: address, Facility: "local0", AppName: "quote-service"})
+	if err != nil {
+		t.Fatalf("newSyslogWriter: %v", err)
+	}
+	t.Cleanup(func() { writer.Close() })
+
+	handler, err := newFormatHandler(FormatJSON, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slog.New(newSyslogHandler(handler, writer)), writer
+}
+
+func TestSyslogUDPHeader(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l, _ := newTestSyslogLogger(t, "udp", conn.LocalAddr().String())
+	l.Warn("Quote served")
+	l.Error("Lookup failed")
+
+	// local0 is facility 16, warning is severity 4 and error 3
+	for _, wantPRI := range []int{16*8 + 4, 16*8 + 3} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 64*1024)
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+
+		match := syslogHeader.FindStringSubmatch(string(buf[:n]))
+		if match == nil {
+			t.Fatalf("not an RFC 5424 message: %q", buf[:n])
+		}
+		if pri, _ := strconv.Atoi(match[1]); pri != wantPRI {
+			t.Errorf("PRI = %d, want %d", pri, wantPRI)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, match[2]); err != nil {
+			t.Errorf("TIMESTAMP %q: %v", match[2], err)
+		}
+		if match[4] != "quote-service" {
+			t.Errorf("APP-NAME = %q, want quote-service", match[4])
+		}
+		if match[5] != strconv.Itoa(os.Getpid()) {
+			t.Errorf("PROCID = %q, want %d", match[5], os.Getpid())
+		}
+		if !json.Valid([]byte(match[6])) {
+			t.Errorf("MSG is not the JSON record: %q", match[6])
+		}
+	}
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	l, _ := newTestSyslogLogger(t, "tcp", listener.Addr().String())
+	messages := []string{"First message", "Second message\nwith a newline"}
+	for _, msg := range messages {
+		l.Info(msg)
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, want := range messages {
+		lengthStr, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatalf("read length: %v", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(lengthStr, " "))
+		if err != nil {
+			t.Fatalf("invalid frame length %q", lengthStr)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+
+		match := syslogHeader.FindStringSubmatch(string(frame))
+		if match == nil {
+			t.Fatalf("not an RFC 5424 message: %q", frame)
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(match[6]), &record); err != nil || record["msg"] != want {
+			t.Errorf("MSG = %q, want a record of %q", match[6], want)
+		}
+	}
+}
+
+func TestSyslogWriteDoesNotBlock(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The server accepts the connection but never reads, so the sender stalls once the socket
+	// buffers are full
+	l, writer := newTestSyslogLogger(t, "tcp", listener.Addr().String())
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	payload := strings.Repeat("x", 32*1024)
+	start := time.Now()
+	for range 2 * syslogQueueSize {
+		l.Info("Large message", "payload", payload)
+	}
+	// Waiting for the server would take at least one write timeout
+	if elapsed := time.Since(start); elapsed >= syslogWriteTimeout {
+		t.Errorf("logging took %v, want it not to wait for the server", elapsed)
+	}
+	if writer.dropped.Load() == 0 {
+		t.Error("no messages were dropped with a stalled server and a full queue")
+	}
+
+	start = time.Now()
+	writer.Close()
+	if elapsed := time.Since(start); elapsed > syslogWriteTimeout+time.Second {
+		t.Errorf("Close took %v, want at most about one write timeout", elapsed)
+	}
+	if _, err := writer.Write([]byte("after close")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func TestSyslogServerStartingLater(t *testing.T) {
+	// Reserve an address, then stop listening on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	start := time.Now()
+	l, writer := newTestSyslogLogger(t, "tcp", address)
+	if elapsed := time.Since(start); elapsed >= syslogDialTimeout {
+		t.Errorf("creating the writer took %v, want it not to connect", elapsed)
+	}
+	l.Info("Lost message")
+	for writer.dropped.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("address %s was taken in the meantime: %v", address, err)
+	}
+	defer listener.Close()
+
+	// Messages are dropped until a reconnect after the backoff succeeds
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+				l.Info("Retried message")
+			}
+		}
+	}()
+
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("the writer didn't reconnect: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('}')
+		if err != nil {
+			t.Fatalf("no notice about the dropped messages: %v", err)
+		}
+		if strings.Contains(line, "Dropped") {
+			break
+		}
+	}
+}
+
+func TestSyslogInvalidConfig(t *testing.T) {
+	tests := []SyslogSinkConfig{
+		{Network: "unix", Address: "localhost:514"},
+		{Network: "udp"},
+		{Network: "udp", Address: "localhost"},
+		{Network: "udp", Address: "localhost:514", Facility: "local8"},
+	}
+
+	for _, config := range tests {
+		if writer, err := newSyslogWriter(config); err == nil {
+			writer.Close()
+			t.Errorf("newSyslogWriter(%+v) succeeded", config)
+		}
+	}
+}
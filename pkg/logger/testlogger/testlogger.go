@@ -0,0 +1,284 @@
+// Package testlogger provides a logger.Logger that records entries in memory so tests can assert
+// on what was logged.
+package testlogger
+
+import (
+	"context"
+	"fmt"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Level of a recorded entry
+type Level string
+
+const (
+	LevelDebug Level = "debug"
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+// badKey is the key of a trailing value without a key, like in log/slog
+const badKey = "!BADKEY"
+
+// Entry is a recorded log message
+type Entry struct {
+	Level   Level
+	Message string
+	// Attrs holds the key-value pairs and fields of the message, including those added by With.
+	// Values have the type stored by the field constructors (e.g. int64 for field.Int), key-value
+	// pairs are strings, groups are nested maps and errors are recorded as their message.
+	Attrs map[string]any
+	// TraceID is the trace ID of the context, empty for the methods without a context
+	TraceID string
+	// OtelTraceID is the OpenTelemetry trace ID of the context, empty when it has none
+	OtelTraceID string
+	// Component is the name given to Named, empty for the root logger
+	Component string
+}
+
+// Attr returns the attribute key of e
+func (e Entry) Attr(key string) (any, bool) {
+	value, ok := e.Attrs[key]
+	return value, ok
+}
+
+// String returns the attribute key of e formatted with fmt, or "" if it's missing
+func (e Entry) String(key string) string {
+	value, ok := e.Attrs[key]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// recorder holds the entries of a logger and the loggers derived from it
+type recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Logger records every message, regardless of level. Loggers returned by Named and With record to
+// the same entries as their parent.
+type Logger struct {
+	recorder  *recorder
+	component string
+	fields    []field.Field
+}
+
+var _ logger.Logger = (*Logger)(nil)
+
+// New creates a logger without entries
+func New() *Logger {
+	return &Logger{recorder: &recorder{}}
+}
+
+// Named implements logger.Logger.
+func (l *Logger) Named(component string) logger.Logger {
+	return &Logger{recorder: l.recorder, component: component, fields: l.fields}
+}
+
+// With implements logger.Logger.
+func (l *Logger) With(fields ...field.Field) logger.Logger {
+	return &Logger{recorder: l.recorder, component: l.component, fields: append(slices.Clone(l.fields), fields...)}
+}
+
+// Debug implements logger.Logger.
+func (l *Logger) Debug(msg string, keysAndValues ...string) {
+	l.recordPairs(context.Background(), LevelDebug, msg, keysAndValues)
+}
+
+// Info implements logger.Logger.
+func (l *Logger) Info(msg string, keysAndValues ...string) {
+	l.recordPairs(context.Background(), LevelInfo, msg, keysAndValues)
+}
+
+// Warn implements logger.Logger.
+func (l *Logger) Warn(msg string, keysAndValues ...string) {
+	l.recordPairs(context.Background(), LevelWarn, msg, keysAndValues)
+}
+
+// Error implements logger.Logger.
+func (l *Logger) Error(msg string, keysAndValues ...string) {
+	l.recordPairs(context.Background(), LevelError, msg, keysAndValues)
+}
+
+// DebugWithCtx implements logger.Logger.
+func (l *Logger) DebugWithCtx(ctx context.Context, msg string, keysAndValues ...string) {
+	l.recordPairs(ctx, LevelDebug, msg, keysAndValues)
+}
+
+// InfoWithCtx implements logger.Logger.
+func (l *Logger) InfoWithCtx(ctx context.Context, msg string, keysAndValues ...string) {
+	l.recordPairs(ctx, LevelInfo, msg, keysAndValues)
+}
+
+// WarnWithCtx implements logger.Logger.
+func (l *Logger) WarnWithCtx(ctx context.Context, msg string, keysAndValues ...string) {
+	l.recordPairs(ctx, LevelWarn, msg, keysAndValues)
+}
+
+// ErrorWithCtx implements logger.Logger.
+func (l *Logger) ErrorWithCtx(ctx context.Context, msg string, keysAndValues ...string) {
+	l.recordPairs(ctx, LevelError, msg, keysAndValues)
+}
+
+// DebugFields implements logger.Logger.
+func (l *Logger) DebugFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.record(ctx, LevelDebug, msg, fields)
+}
+
+// InfoFields implements logger.Logger.
+func (l *Logger) InfoFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.record(ctx, LevelInfo, msg, fields)
+}
+
+// WarnFields implements logger.Logger.
+func (l *Logger) WarnFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.record(ctx, LevelWarn, msg, fields)
+}
+
+// ErrorFields implements logger.Logger.
+func (l *Logger) ErrorFields(ctx context.Context, msg string, fields ...field.Field) {
+	l.record(ctx, LevelError, msg, fields)
+}
+
+// recordPairs converts key-value pairs to fields and records them
+func (l *Logger) recordPairs(ctx context.Context, level Level, msg string, keysAndValues []string) {
+	fields := make([]field.Field, 0, (len(keysAndValues)+1)/2) //nolint:mnd
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, field.String(badKey, keysAndValues[i]))
+			break
+		}
+		fields = append(fields, field.String(keysAndValues[i], keysAndValues[i+1]))
+	}
+
+	l.record(ctx, level, msg, fields)
+}
+
+func (l *Logger) record(ctx context.Context, level Level, msg string, fields []field.Field) {
+	attrs := fieldsToMap(l.fields)
+	for key, value := range fieldsToMap(fields) {
+		attrs[key] = value
+	}
+
+	entry := Entry{
+		Level:       level,
+		Message:     msg,
+		Attrs:       attrs,
+		TraceID:     logger.TraceID(ctx),
+		OtelTraceID: logger.OtelTraceID(ctx),
+		Component:   l.component,
+	}
+
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+	l.recorder.entries = append(l.recorder.entries, entry)
+}
+
+func fieldsToMap(fields []field.Field) map[string]any {
+	attrs := make(map[string]any, len(fields))
+	for _, f := range fields {
+		switch value := f.Value.(type) {
+		case field.Fields:
+			attrs[f.Key] = fieldsToMap(value)
+		case error:
+			attrs[f.Key] = value.Error()
+		default:
+			attrs[f.Key] = value
+		}
+	}
+
+	return attrs
+}
+
+// Entries returns the recorded entries, oldest first
+func (l *Logger) Entries() []Entry {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	return slices.Clone(l.recorder.entries)
+}
+
+// Filter returns the entries for which match returns true, oldest first
+func (l *Logger) Filter(match func(Entry) bool) []Entry {
+	return slices.DeleteFunc(l.Entries(), func(e Entry) bool { return !match(e) })
+}
+
+// ByLevel returns the entries of level
+func (l *Logger) ByLevel(level Level) []Entry {
+	return l.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// ByMessage returns the entries whose message contains substr
+func (l *Logger) ByMessage(substr string) []Entry {
+	return l.Filter(func(e Entry) bool { return strings.Contains(e.Message, substr) })
+}
+
+// ByTraceID returns the entries logged with the trace ID
+func (l *Logger) ByTraceID(traceID string) []Entry {
+	return l.Filter(func(e Entry) bool { return e.TraceID == traceID })
+}
+
+// Find returns the first entry of level whose message contains substr
+func (l *Logger) Find(level Level, substr string) (Entry, bool) {
+	entries := l.Filter(func(e Entry) bool { return e.Level == level && strings.Contains(e.Message, substr) })
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[0], true
+}
+
+// Has reports whether an entry of level has a message containing substr
+func (l *Logger) Has(level Level, substr string) bool {
+	_, ok := l.Find(level, substr)
+	return ok
+}
+
+// Len returns the number of recorded entries
+func (l *Logger) Len() int {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	return len(l.recorder.entries)
+}
+
+// Reset removes all recorded entries, including those of the loggers derived from the same New
+func (l *Logger) Reset() {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	l.recorder.entries = nil
+}
+
+// String formats the entries one per line, for failure messages
+func (l *Logger) String() string {
+	var b strings.Builder
+	for _, e := range l.Entries() {
+		fmt.Fprintf(&b, "%s %q", e.Level, e.Message)
+		if e.Component != "" {
+			fmt.Fprintf(&b, " component=%s", e.Component)
+		}
+		if e.TraceID != "" {
+			fmt.Fprintf(&b, " traceID=%s", e.TraceID)
+		}
+		if e.OtelTraceID != "" {
+			fmt.Fprintf(&b, " otelTraceID=%s", e.OtelTraceID)
+		}
+		keys := make([]string, 0, len(e.Attrs))
+		for key := range e.Attrs {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%v", key, e.Attrs[key])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package testlogger
+
+import (
+	"context"
+	"errors"
+	"quote-service/pkg/logger"
+	"quote-service/pkg/logger/field"
+	"testing"
+)
+
+func TestLoggerRecordsEntries(t *testing.T) {
+	l := New()
+	ctx := logger.WithTraceID(context.Background(), "trace-1")
+
+	l.Info("Started", "port", "8080", "dangling")
+	jobLogger := l.Named("jobs").With(field.String("job_id", "42"))
+	jobLogger.ErrorFields(ctx, "Job failed", field.Err(errors.New("boom")),
+		field.Group("budget", field.Int("used_mb", 10)))
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	started := entries[0]
+	if started.Level != LevelInfo || started.TraceID != "" || started.Component != "" {
+		t.Errorf("unexpected entry %+v", started)
+	}
+	if started.String("port") != "8080" || started.String(badKey) != "dangling" {
+		t.Errorf("unexpected attributes %v", started.Attrs)
+	}
+
+	failed, ok := l.Find(LevelError, "failed")
+	if !ok {
+		t.Fatalf("Find didn't return the error entry")
+	}
+	if failed.TraceID != "trace-1" || failed.Component != "jobs" {
+		t.Errorf("unexpected entry %+v", failed)
+	}
+	if failed.String("job_id") != "42" || failed.String("error") != "boom" {
+		t.Errorf("unexpected attributes %v", failed.Attrs)
+	}
+	if budget, _ := failed.Attr("budget"); budget.(map[string]any)["used_mb"] != int64(10) {
+		t.Errorf("unexpected group %v", budget)
+	}
+
+	if len(l.ByTraceID("trace-1")) != 1 || len(l.ByMessage("Start")) != 1 || l.Has(LevelWarn, "") {
+		t.Errorf("unexpected query results:\n%s", l)
+	}
+
+	jobLogger.Warn("Retrying")
+	l.Reset()
+	if l.Len() != 0 {
+		t.Errorf("Reset left %d entries", l.Len())
+	}
+}
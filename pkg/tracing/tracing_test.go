@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  error
+	}{
+		{name: "default", exporter: ""},
+		{name: "none", exporter: ExporterNone},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "unknown", exporter: "jaeger", wantErr: ErrUnknownExporter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), SetupArgs{
+				Exporter:    tt.exporter,
+				SampleRatio: 1,
+				ServiceName: "quote-service",
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Setup() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+
+			// Trace context is propagated even when no spans are exported
+			fields := otel.GetTextMapPropagator().Fields()
+			for _, want := range []string{"traceparent", "baggage"} {
+				if !slices.Contains(fields, want) {
+					t.Errorf("propagator fields = %v, missing %q", fields, want)
+				}
+			}
+		})
+	}
+}